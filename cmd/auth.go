@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/gitea"
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/spf13/cobra"
+)
+
+// the supported migration targets
+const (
+	targetGithub = "github"
+	targetGitea  = "gitea"
+)
+
+// environment variable that is checked for a gitea token
+const giteaTokenEnvVar = "GITEA_TOKEN"
+
+// AuthVariables hold the credentials for authenticating with the github API
+// either as a user (personal access token) or as a github app installation
+type AuthVariables struct {
+	Target         string
+	URL            string
+	Endpoint       string
+	Token          string
+	TokenFile      string
+	AppId          string
+	AppKeyPath     string
+	InstallationId int64
+	Record         string
+	Replay         string
+
+	// the recorder of the client (with --record), closed by Close
+	recorder *github.Recorder
+}
+
+func authFlags(cmd *cobra.Command, auth *AuthVariables) {
+	cmd.Flags().StringVar(&auth.Target, "target", targetGithub, "the kind of the migration target (github or gitea, which includes forgejo)")
+	cmd.Flags().StringVar(&auth.URL, "url", "", "the URL of the gitea instance (required with --target gitea)")
+	cmd.Flags().StringVar(&auth.Endpoint, "api-url", "", "the URL of the github API (defaults to https://api.github.com)")
+	cmd.Flags().StringVarP(&auth.Token, "token", "t", "",
+		fmt.Sprintf("the API token for authenticating with github API (also read from --token-file or looked up in %v, the gh CLI configuration and the OS keyring)", github.TokenEnvVars))
+	cmd.Flags().StringVar(&auth.TokenFile, "token-file", "", "file that contains the API token (must be readable only by its owner)")
+	cmd.Flags().StringVar(&auth.AppId, "app-id", "", "the ID of the github app to authenticate as (instead of --token)")
+	cmd.Flags().StringVar(&auth.AppKeyPath, "app-key", "", "the private key (PEM file) of the github app")
+	cmd.Flags().Int64Var(&auth.InstallationId, "installation-id", 0, "the ID of the github app's installation in the target repo's account")
+	cmd.Flags().StringVar(&auth.Record, "record", "", "record all API requests and responses to this cassette file (JSONL, tokens redacted)")
+	cmd.Flags().StringVar(&auth.Replay, "replay", "", "serve the API responses from this cassette file instead of contacting github")
+	cmd.MarkFlagsMutuallyExclusive("record", "replay")
+}
+
+// NewTarget returns the REST client or, if dryRun is true, a simulator that reads
+// the state of the repo through the client but performs all changes in memory
+// delay is the minimum interval between requests that create content
+func (auth *AuthVariables) NewTarget(dryRun, debug bool, delay time.Duration) (github.Target, error) {
+	var (
+		client github.Target
+		err    error
+	)
+	switch auth.Target {
+	case targetGithub:
+		client, err = auth.NewClient(dryRun, debug, github.WithScheduler(github.NewScheduler(delay)))
+	case targetGitea:
+		client, err = auth.newGiteaClient(debug, gitea.WithScheduler(github.NewFloorScheduler(delay)))
+	default:
+		err = configError(fmt.Errorf("unknown target: %s (expected %s or %s)", auth.Target, targetGithub, targetGitea))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return github.NewSimulator(client), nil
+	}
+	return client, nil
+}
+
+// githubHost returns the host whose credentials are looked up in the gh CLI configuration
+// and the OS keyring: the host of --api-url without its "api." prefix (github.com by default)
+func (auth *AuthVariables) githubHost() string {
+	u, err := url.Parse(auth.Endpoint)
+	if auth.Endpoint == "" || err != nil || u.Hostname() == "" {
+		return "github.com"
+	}
+	return strings.TrimPrefix(u.Hostname(), "api.")
+}
+
+// NewClient returns a github client that authenticates with the specified credentials
+func (auth *AuthVariables) NewClient(dryRun, debug bool, opts ...github.Option) (*github.Client, error) {
+	if auth.Target != targetGithub {
+		return nil, configError(fmt.Errorf("this command supports only the %s target", targetGithub))
+	}
+	opts = append(opts, github.WithEndpoint(auth.Endpoint))
+	if auth.Replay != "" {
+		replayer, err := github.LoadCassette(auth.Replay)
+		if err != nil {
+			return nil, configError(err)
+		}
+		// no credentials are required and the recorded responses are served without pacing
+		opts = append(opts, github.WithTransport(replayer), github.WithScheduler(github.NewVirtualScheduler()))
+		return github.NewClient("replay", dryRun, debug, opts...), nil
+	}
+
+	token := ""
+	if auth.AppId == "" {
+		resolved, source, err := github.ResolveToken(auth.Token, auth.TokenFile, auth.githubHost())
+		if err != nil {
+			return nil, configError(err)
+		}
+		if debug {
+			log.Printf("using github token from %s", source)
+		}
+		token = resolved
+	} else {
+		if auth.AppKeyPath == "" || auth.InstallationId == 0 {
+			return nil, configError(fmt.Errorf("--app-key and --installation-id are required with --app-id"))
+		}
+		key, err := os.ReadFile(auth.AppKeyPath)
+		if err != nil {
+			return nil, configError(fmt.Errorf("failed to read the app's private key: %v", err))
+		}
+		tokens, err := github.NewAppTokenSource(auth.Endpoint, auth.AppId, auth.InstallationId, key)
+		if err != nil {
+			return nil, configError(err)
+		}
+		opts = append(opts, github.WithTokenSource(tokens))
+	}
+
+	if auth.Record != "" {
+		recorder, err := github.NewRecorder(auth.Record, nil, token)
+		if err != nil {
+			return nil, configError(err)
+		}
+		auth.recorder = recorder
+		opts = append(opts, github.WithTransport(recorder))
+	}
+	return github.NewClient(token, dryRun, debug, opts...), nil
+}
+
+// Close closes the cassette that the requests are recorded to (if any)
+// It must be called once the command has finished using the client.
+func (auth *AuthVariables) Close() error {
+	if auth.recorder == nil {
+		return nil
+	}
+	err := auth.recorder.Close()
+	auth.recorder = nil
+	return err
+}
+
+func (auth *AuthVariables) newGiteaClient(debug bool, opts ...gitea.Option) (*gitea.Client, error) {
+	if auth.URL == "" {
+		return nil, configError(fmt.Errorf("--url is required with --target %s", targetGitea))
+	}
+	if auth.Replay != "" {
+		replayer, err := github.LoadCassette(auth.Replay)
+		if err != nil {
+			return nil, configError(err)
+		}
+		opts = append(opts, gitea.WithTransport(replayer), gitea.WithScheduler(github.NewVirtualScheduler()))
+		return gitea.NewClient(auth.URL, "replay", debug, opts...), nil
+	}
+
+	// an explicit token file is never overridden by the environment
+	token := auth.Token
+	if token == "" && auth.TokenFile != "" {
+		var err error
+		if token, err = github.ReadTokenFile(auth.TokenFile); err != nil {
+			return nil, configError(err)
+		}
+	}
+	if token == "" {
+		token = strings.TrimSpace(os.Getenv(giteaTokenEnvVar))
+	}
+	if token == "" {
+		return nil, configError(fmt.Errorf("no gitea token found (use --token, --token-file or %s)", giteaTokenEnvVar))
+	}
+
+	if auth.Record != "" {
+		recorder, err := github.NewRecorder(auth.Record, nil, token)
+		if err != nil {
+			return nil, configError(err)
+		}
+		auth.recorder = recorder
+		opts = append(opts, gitea.WithTransport(recorder))
+	}
+	return gitea.NewClient(auth.URL, token, debug, opts...), nil
+}
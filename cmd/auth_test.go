@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GithubHost(t *testing.T) {
+	for endpoint, host := range map[string]string{
+		"":                               "github.com",
+		"https://api.github.com":         "github.com",
+		"https://ghe.example.com/api/v3": "ghe.example.com",
+		"https://api.acme.ghe.com":       "acme.ghe.com",
+		"http://127.0.0.1:8080":          "127.0.0.1",
+	} {
+		auth := &AuthVariables{Endpoint: endpoint}
+		assert.Equal(t, host, auth.githubHost(), endpoint)
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/spf13/cobra"
+)
+
+// checklist collects the outcomes of the doctor's checks
+type checklist struct {
+	out    io.Writer
+	failed int
+}
+
+func (c *checklist) pass(format string, args ...interface{}) {
+	fmt.Fprintf(c.out, "[PASS] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (c *checklist) warn(format string, args ...interface{}) {
+	fmt.Fprintf(c.out, "[WARN] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (c *checklist) fail(format string, args ...interface{}) {
+	c.failed += 1
+	fmt.Fprintf(c.out, "[FAIL] %s\n", fmt.Sprintf(format, args...))
+}
+
+func DoctorCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		repo   string
+		auth   AuthVariables
+		labels []string
+
+		descr = "Check the token, the target repo and the settings before a migration"
+		cmd   = &cobra.Command{
+			Use:   "doctor",
+			Short: descr,
+			Long:  descr,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				ctx := cmd.Context()
+				checks := &checklist{out: cmd.OutOrStdout()}
+
+				client, err := auth.NewClient(false, globals.Debug)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
+
+				// token scopes
+				scopes, hasScopes, err := github.Scopes(ctx, client)
+				if err != nil {
+					checks.fail("token: %v", err)
+					return doctorResult(checks)
+				}
+				if !hasScopes {
+					checks.warn("token: no OAuth scopes reported (fine-grained or app token); permissions are checked against the repo")
+				} else if contains(scopes, "repo") || contains(scopes, "public_repo") {
+					checks.pass("token: scopes %v", scopes)
+				} else {
+					checks.fail("token: scopes %v do not include 'repo' or 'public_repo'", scopes)
+				}
+
+				// repo metadata
+				repository, err := github.GetRepo(ctx, client, repo)
+				if err != nil {
+					checks.fail("repo %s: %v", repo, err)
+					return doctorResult(checks)
+				}
+				checks.pass("repo %s: found (visibility: %s)", repository.FullName, repository.Visibility)
+				if hasScopes && repository.Private && !contains(scopes, "repo") {
+					checks.fail("repo %s: is private and requires the 'repo' scope", repo)
+				}
+				if repository.HasIssues {
+					checks.pass("repo %s: issues are enabled", repo)
+				} else {
+					checks.fail("repo %s: issues are disabled", repo)
+				}
+				if repository.Permissions.Push || repository.Permissions.Admin {
+					checks.pass("repo %s: write access", repo)
+				} else {
+					checks.fail("repo %s: no write access (required for labels and assignees)", repo)
+				}
+				// closed issues and pull requests take up issue numbers too
+				if last, err := github.LastIssueNumber(ctx, client, repo); err != nil {
+					checks.fail("repo %s: %v", repo, err)
+				} else if last == 0 {
+					checks.pass("repo %s: has no issues or pull requests (issue numbers will follow the gitlab IDs)", repo)
+				} else {
+					checks.warn("repo %s: has issues or pull requests up to #%d (issue numbers may not follow the gitlab IDs)", repo, last)
+				}
+
+				// assignees
+				users := []string{}
+				for user := range globals.UserMappings {
+					users = append(users, user)
+				}
+				sort.Strings(users)
+				for _, user := range users {
+					if ok, err := github.IsAssignable(ctx, client, repo, user); err != nil {
+						checks.fail("user %s: %v", user, err)
+					} else if ok {
+						checks.pass("user %s: is assignable", user)
+					} else {
+						checks.fail("user %s: is not assignable in %s (not a collaborator?)", user, repo)
+					}
+				}
+
+				// labels (github.ClosedLabel is attached to closed gitlab issues)
+				for _, label := range append(labels, github.ClosedLabel) {
+					if ok, err := github.LabelExists(ctx, client, repo, label); err != nil {
+						checks.fail("label %s: %v", label, err)
+					} else if ok {
+						checks.pass("label %s: exists", label)
+					} else if repository.Permissions.Push || repository.Permissions.Admin {
+						checks.pass("label %s: will be created", label)
+					} else {
+						checks.fail("label %s: does not exist and cannot be created", label)
+					}
+				}
+
+				// rate limit vs. the migration plan
+				rate, err := client.RateLimit(ctx)
+				if err != nil {
+					checks.fail("rate limit: %v", err)
+					return doctorResult(checks)
+				}
+				if !globals.HasSource() {
+					checks.pass("rate limit: %d/%d remaining (reset at %v)", rate.Remaining, rate.Limit, rate.ResetAt)
+					return doctorResult(checks)
+				}
+				issues, err := globals.Issues(ctx)
+				if err != nil {
+					checks.fail("%s: %v", globals.SourceName(), err)
+					return doctorResult(checks)
+				}
+				checks.pass("%s: %d issues", globals.SourceName(), len(issues))
+				requests, content := plan(issues)
+				msg := fmt.Sprintf("rate limit: %d/%d remaining (reset at %v) for ~%d requests (%d content creations, ~%v)",
+					rate.Remaining, rate.Limit, rate.ResetAt.Format(time.RFC3339), requests, content, estimate(content))
+				if requests <= rate.Remaining {
+					checks.pass("%s", msg)
+				} else {
+					checks.warn("%s: the migration will pause until the quota resets", msg)
+				}
+
+				return doctorResult(checks)
+			},
+		}
+	)
+
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
+	cmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "a comma-separated list of labels to be attached to the issue")
+	authFlags(cmd, &auth)
+	cmd.MarkFlagRequired("repo")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
+
+func doctorResult(checks *checklist) error {
+	if checks.failed > 0 {
+		return configError(fmt.Errorf("%d check(s) failed", checks.failed))
+	}
+	fmt.Fprintln(checks.out, "All checks passed")
+	return nil
+}
+
+// plan returns the approximate number of API requests and of content-creating requests
+// that are required for importing the issues (placeholders included) and their comments
+func plan(issues []*gitlab.Issue) (requests, content int) {
+	if len(issues) == 0 {
+		return 0, 0
+	}
+	last := issues[len(issues)-1].Id
+	// issues and placeholders
+	content = last
+	for _, issue := range issues {
+		content += len(issue.Comments)
+	}
+	// scanning the existing issues and listing the comments of each issue
+	requests = content + last/100 + 1 + len(issues)
+	return requests, content
+}
+
+// estimate returns the minimum duration of content creation under github's secondary limits
+func estimate(content int) time.Duration {
+	perMinute := time.Duration(math.Ceil(float64(content)/80)) * time.Minute
+	perHour := time.Duration(math.Ceil(float64(content)/500)-1) * time.Hour
+	if perHour > perMinute {
+		return perHour
+	}
+	return perMinute
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
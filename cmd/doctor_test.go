@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func doctor(srv *githubtest.Server) (string, error) {
+	out := &bytes.Buffer{}
+	root := New()
+	root.SetOut(out)
+	root.SetArgs([]string{"doctor", "--repo", testRepo, "--token", "test-token", "--api-url", srv.URL})
+	err := root.Execute()
+	return out.String(), err
+}
+
+func Test_Doctor(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	out, err := doctor(srv)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "[PASS] repo "+testRepo+": has no issues or pull requests")
+	assert.Contains(t, out, "[PASS] label closed: will be created")
+	assert.Contains(t, out, "All checks passed")
+
+	// a pull request takes up an issue number
+	srv.AddPullRequest(testRepo, "a pull request")
+	out, err = doctor(srv)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "[WARN] repo "+testRepo+": has issues or pull requests up to #1")
+
+	srv.Repo(testRepo).HasIssues = false
+	out, err = doctor(srv)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+	assert.Contains(t, out, "[FAIL] repo "+testRepo+": issues are disabled")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// process exit codes
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitConfig  = 2
+	ExitParse   = 3
+	ExitAuth    = 4
+	ExitAPI     = 5
+	// the run was interrupted by a signal
+	ExitInterrupted = 130
+)
+
+// ExitError associates an error with the exit code of the process
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+func configError(err error) error {
+	return &ExitError{Code: ExitConfig, Err: err}
+}
+
+func parseError(err error) error {
+	return &ExitError{Code: ExitParse, Err: err}
+}
+
+// ExitCode returns the process exit code that corresponds to err
+// API errors are classified as auth or generic API failures
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	var statusErr *github.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.IsAuth() {
+			return ExitAuth
+		}
+		return ExitAPI
+	}
+	var gitlabErr *gitlab.StatusError
+	if errors.As(err, &gitlabErr) {
+		if gitlabErr.IsAuth() {
+			return ExitAuth
+		}
+		return ExitAPI
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return ExitAPI
+	}
+	return ExitFailure
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExitCode_InvalidFlags(t *testing.T) {
+	// missing required flag
+	err := execute("import", "--export", "testdata/issues.ndjson")
+	assert.NotNil(t, err)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+
+	// unknown flag
+	err = execute("import", "--export", "testdata/issues.ndjson", "--repo", testRepo, "--unknown")
+	assert.Equal(t, ExitConfig, ExitCode(err))
+
+	// mutually exclusive flags
+	err = execute("import", "--export", "testdata/issues.ndjson", "--repo", testRepo, "--record", "a", "--replay", "b")
+	assert.Equal(t, ExitConfig, ExitCode(err))
+}
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/kkentzo/gl-to-gh/github"
-	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/kkentzo/gl-to-gh/migrate"
 	"github.com/spf13/cobra"
 )
 
@@ -18,152 +18,118 @@ func ImportCommand(globals *GlobalVariables) *cobra.Command {
 		startFromId  int
 		endAtId      int
 		repo         string
-		token        string
+		auth         AuthVariables
 		labels       []string
 		dryRun       bool
+		reportPath   string
+		mappingPath  string
+		existing     string
+		workers      int
+		links        string
+		tasks        string
 
 		descr = "Mass import of gitlab issues to github"
 		cmd   = &cobra.Command{
 			Use:   "import",
 			Short: descr,
 			Long:  descr,
-			Run: func(cmd *cobra.Command, args []string) {
-				client := github.NewClient(token, dryRun, globals.Debug)
-
-				t0 := time.Now()
-
-				defer func() { log.Printf("Duration: %v\nRequest Count: %d", time.Now().Sub(t0), client.RequestCount()) }()
+			RunE: func(cmd *cobra.Command, args []string) error {
+				target, err := auth.NewTarget(dryRun, globals.Debug, delay)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
 
-				mappings := ReverseMapping(globals.UserMappings)
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
 
-				// parse ndjson
-				issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+				issues, err := globals.Issues(cmd.Context())
 				if err != nil {
-					log.Printf("error: %v", err)
-					return
+					return err
 				}
 
 				if len(issues) == 0 {
-					log.Printf("no issues found in file %s", globals.ExportPath)
-					return
+					return parseError(fmt.Errorf("no issues found in %s", globals.SourceName()))
 				}
 
-				issueMap := map[int]*github.Issue{}
-
-				for _, issue := range issues {
-					issueMap[issue.Id], err = github.New(issue, mappings, labels, globals.ReplacePatterns)
-					if err != nil {
-						log.Printf("[#%d] failed to convert issue: %v", issue.Id, err)
-						return
+				var mapping *migrate.Mapping
+				if mappingPath != "" {
+					if mapping, err = migrate.LoadMapping(mappingPath); err != nil {
+						return configError(err)
+					}
+					if dryRun {
+						mapping.Discard()
 					}
 				}
 
-				last := issues[len(issues)-1]
-				start := startFromId
-				end := last.Id
-				if endAtId > 0 {
-					end = endAtId
-				}
-				if commentsOnly {
-					if reverse {
-						start, end = end, start
-						log.Printf("Reversing order [comments=%v] [reverse=%v] [start=%d] [end=%d]", commentsOnly, reverse, start, end)
-					} else {
-						log.Printf("--reverse can be specified only in conjuction with --comments [reverse=%v] [comments=%v]",
-							reverse, commentsOnly)
-						return
-					}
-				} else {
-					log.Printf("[start=%d] [end=%d] [delay=%v]", start, end, delay)
+				migrator, err := migrate.New(target, issues, migrate.Options{
+					Repo:         repo,
+					Labels:       labels,
+					Start:        startFromId,
+					End:          endAtId,
+					CommentsOnly: commentsOnly,
+					Reverse:      reverse,
+					Existing:     existing,
+					Links:        links,
+					Tasks:        tasks,
+					Workers:      workers,
+					Render:       opts,
+					Mapping:      mapping,
+					Events: migrate.Events{
+						IssueCreated: func(iid int, issue *github.Issue) {
+							log.Printf("[#%d] %s (%d comments)", iid, issue.Title, len(issue.Comments()))
+						},
+						PlaceholderCreated: func(iid int, issue *github.Issue) {
+							log.Printf("[#%d] %s (placeholder)", iid, issue.Title)
+						},
+						IssueExisting: func(iid int, existing *github.Issue, updated bool) {
+							log.Printf("[#%d] %s (already imported as #%d) [updated=%v]", iid, existing.Title, existing.Number, updated)
+						},
+						CommentPosted: func(iid int, issue *github.Issue, comment *github.Comment) {
+							log.Printf("[#%d] %s (comment posted)", iid, issue.Title)
+						},
+					},
+				})
+				if err != nil {
+					return configError(err)
 				}
 
-				iid := start
-				for {
-					// check iteration
-					if reverse {
-						if iid < end {
-							break
-						}
-					} else {
-						if iid > end {
-							break
-						}
-					}
+				start, end := migrator.Range()
+				log.Printf("[start=%d] [end=%d] [comments=%v] [reverse=%v] [delay=%v]", start, end, commentsOnly, reverse, delay)
 
-					// perform operations
-					if commentsOnly {
-						if issue, err := PostComments(iid, issueMap, client, repo, labels, delay); err != nil {
-							log.Printf("%v", err)
-							return
-						} else {
-							if issue == nil {
-								log.Printf("[#%d] Issue does not exist (0 comments)", iid)
-							} else {
-								log.Printf("[#%d] %s (%d comments)", iid, issue.Title, len(issue.Comments()))
-							}
-						}
-					} else {
-						if issue, err := PostIssue(iid, issueMap, client, repo, labels); err != nil {
-							log.Printf("%v", err)
-							return
-						} else {
-							log.Printf("[#%d] %s (%d comments)", iid, issue.Title, len(issue.Comments()))
-						}
-						time.Sleep(delay)
-					}
+				ctx, stop := interruptible(cmd.Context())
+				defer stop()
+				err = migrator.Run(ctx)
 
-					// advance iteration
-					if reverse {
-						iid--
-					} else {
-						iid++
+				report := migrator.Report()
+				log.Printf("Summary:\n%s", report.Summarize())
+				if reportPath != "" {
+					if werr := report.Write(reportPath); werr != nil {
+						log.Printf("error: %v", werr)
 					}
 				}
+				return err
 			},
 		}
 	)
 
-	cmd.Flags().BoolVar(&commentsOnly, "comments", false, "import comments only (assumes all issues have been imported and have the same IDs)")
+	cmd.Flags().BoolVar(&commentsOnly, "comments", false, "import comments only (the target issues are resolved through --mapping)")
 	cmd.Flags().BoolVar(&reverse, "reverse", false, "reverse the order of issue IDs (only for comments)")
+	cmd.Flags().IntVar(&workers, "workers", 1, "number of issues whose comments are posted concurrently (only for comments)")
 	cmd.Flags().IntVar(&startFromId, "start", 1, "ID to start the migration from (lower IDs will be skipped)")
 	cmd.Flags().IntVar(&endAtId, "end", 0, "ID to stop the migration at (inclusive)")
 	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "the API token for authenticating with github API")
-	cmd.Flags().DurationVar(&delay, "delay", time.Duration(10*time.Second), "delay between successive API calls")
+	authFlags(cmd, &auth)
+	cmd.Flags().DurationVar(&delay, "delay", 0, "minimum delay between successive API calls that create content (requests are paced according to github's rate limits anyway)")
 	cmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "a comma-separated list of labels to be attached to the issue")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "if true then no API call will be made")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "simulate the changes without making them in github (the state of the repo is still read)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the imported gitlab issues (required with --comments)")
+	cmd.Flags().StringVar(&existing, "existing", migrate.ExistingSkip, "what to do with issues that have already been imported (skip or update)")
+	cmd.Flags().StringVar(&links, "links", migrate.LinksBody, "where the related (linked) issues are listed after the issues are created: body, comment or none (dependencies are added where github supports them)")
+	cmd.Flags().StringVar(&tasks, "tasks", migrate.TasksSubIssues, "how child tasks are migrated: sub-issues or list (API source only)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write a JSON report of the run to the specified file")
 	cmd.MarkFlagRequired("repo")
-	cmd.MarkFlagRequired("token")
-	return requireGlobalFlags(cmd, globals, []string{"export"})
-}
-
-func PostIssue(iid int, issueMap map[int]*github.Issue, client *github.Client, repo string, labels []string) (*github.Issue, error) {
-	if issue, ok := issueMap[iid]; ok {
-		if err := issue.Post(client, repo); err != nil {
-			return issue, fmt.Errorf("[#%d] failed to POST issue: %v\n", iid, err)
-		} else {
-			return issue, nil
-		}
-	} else {
-		// create placeholder issue
-		issue := github.NewPlaceholder(labels)
-		if err := issue.Post(client, repo); err != nil {
-			return issue, fmt.Errorf("[#%d] failed to POST placeholder issue: %v\n", iid, err)
-		} else {
-			return issue, nil
-		}
-	}
-}
-
-func PostComments(iid int, issueMap map[int]*github.Issue, client *github.Client, repo string, labels []string, delay time.Duration) (*github.Issue, error) {
-	if issue, ok := issueMap[iid]; ok {
-		for _, comment := range issue.Comments() {
-			if err := comment.Post(client, repo, iid); err != nil {
-				return nil, fmt.Errorf("[#%d] failed to post comment: %v", iid, err)
-			}
-			time.Sleep(delay)
-		}
-		return issue, nil
-	}
-	return nil, nil
+	return requireGlobalFlags(cmd, globals, []string{})
 }
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/gitea/giteatest"
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/kkentzo/gl-to-gh/gitlab/gitlabtest"
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRepo = "kkentzo/migrated"
+
+func execute(args ...string) error {
+	root := New()
+	root.SetArgs(args)
+	return root.Execute()
+}
+
+func importArgs(srv *githubtest.Server, dir string, extra ...string) []string {
+	args := []string{"import",
+		"--export", "testdata/issues.ndjson",
+		"--repo", testRepo,
+		"--token", "test-token",
+		"--api-url", srv.URL,
+		"--mapping", filepath.Join(dir, "mapping.json"),
+		"--report", filepath.Join(dir, "report.json"),
+		"--users", "kkentzo=482361",
+	}
+	return append(args, extra...)
+}
+
+func readReport(t *testing.T, dir string) *migrate.Report {
+	data, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	assert.Nil(t, err)
+	report := &migrate.Report{}
+	assert.Nil(t, json.Unmarshal(data, report))
+	return report
+}
+
+func Test_Import(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	// issues (with a placeholder for the deleted issue #3)
+	assert.Nil(t, execute(importArgs(srv, dir)...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 4) {
+		assert.Equal(t, "First issue", repo.Issues[0].Title)
+		assert.Equal(t, []string{"kkentzo"}, repo.Issues[0].Assignees)
+		assert.Contains(t, repo.Issues[0].Body, "original author: @kkentzo")
+		assert.Contains(t, repo.Issues[0].Body, "reactions: :thumbsup: 2, :100: 1, :tada: 1\n")
+		assert.Equal(t, []string{"+1", "hooray"}, repo.Issues[0].Reactions)
+		assert.Equal(t, []string{"closed"}, repo.Issues[1].Labels)
+		assert.Equal(t, "[DELETED GITLAB ISSUE]", repo.Issues[2].Title)
+		assert.Equal(t, 4, repo.Issues[3].Number)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, readReport(t, dir).Created)
+
+	// comments (filtered and ordered)
+	assert.Nil(t, execute(importArgs(srv, dir, "--comments", "--workers", "2")...))
+	if assert.Len(t, repo.Issues[0].Comments, 2) {
+		assert.Contains(t, repo.Issues[0].Comments[0].Body, "A first comment")
+		assert.Equal(t, []string{"heart"}, repo.Issues[0].Comments[0].Reactions)
+		assert.Contains(t, repo.Issues[0].Comments[1].Body, "A second comment")
+	}
+	assert.Len(t, repo.Issues[1].Comments, 1)
+
+	// re-running is idempotent
+	assert.Nil(t, execute(importArgs(srv, dir)...))
+	assert.Nil(t, execute(importArgs(srv, dir, "--comments")...))
+	assert.Len(t, repo.Issues, 4)
+	assert.Len(t, repo.Issues[0].Comments, 2)
+	report := readReport(t, dir)
+	assert.Equal(t, 2, report.Issues[0].SkippedComments)
+}
+
+func Test_Import_FailedReactions(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	srv.Fail(githubtest.Fault{Method: http.MethodPost, Path: "/repos/" + testRepo + "/issues/1/reactions", Status: http.StatusForbidden})
+	srv.Fail(githubtest.Fault{Method: http.MethodPost, Path: "/repos/" + testRepo + "/issues/comments/", Status: http.StatusForbidden})
+
+	// the issues and comments are still recorded, so that re-running does not duplicate them
+	for _, args := range [][]string{{}, {}, {"--comments"}, {"--comments"}} {
+		assert.Nil(t, execute(importArgs(srv, dir, args...)...))
+	}
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 4) {
+		assert.Empty(t, repo.Issues[0].Reactions)
+		assert.Len(t, repo.Issues[0].Comments, 2)
+		assert.Empty(t, repo.Issues[0].Comments[0].Reactions)
+	}
+}
+
+func Test_Import_Attribution(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	assert.Nil(t, execute(importArgs(srv, dir, "--attribution", "deferred", "--sanitize", "--end", "1")...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 1) {
+		// the mention was added by a follow-up edit
+		assert.Contains(t, repo.Issues[0].Body, "original author: @kkentzo")
+		assert.Contains(t, repo.Issues[0].Body, "reactions: :thumbsup: 2, :100: 1, :tada: 1\n")
+		assert.Equal(t, []string{"+1", "hooray"}, repo.Issues[0].Reactions)
+		assert.Contains(t, repo.Issues[0].Body, "cc `@someone`")
+	}
+}
+
+// linkedProject returns a gitlab server with a project whose issues are related:
+// #1 relates to #2 and blocks #4, which is also a task of #1 (and #3 was deleted)
+func linkedProject(t *testing.T) *gitlabtest.Server {
+	gl := gitlabtest.NewServer()
+	t.Cleanup(gl.Close)
+	for _, title := range []string{"First issue", "Second issue", "Third issue", "Fourth issue"} {
+		gl.AddIssue("group/project", title, "")
+	}
+	project := gl.Project("group/project")
+	first, second, fourth := project.Issues[0], project.Issues[1], project.Issues[3]
+	first.Links = []*gitlabtest.Link{{Iid: 2, LinkType: "relates_to"}, {Iid: 4, LinkType: "blocks"}}
+	second.Links = []*gitlabtest.Link{{Iid: 1, LinkType: "relates_to"}, {Iid: 7, LinkType: "relates_to", Project: "group/other"}}
+	fourth.Links = []*gitlabtest.Link{{Iid: 1, LinkType: "is_blocked_by"}}
+	fourth.ParentIid = 1
+	project.Issues = []*gitlabtest.Issue{first, second, fourth}
+	return gl
+}
+
+// linkedArgs returns the arguments of an import from the project of linkedProject
+func linkedArgs(gl *gitlabtest.Server, srv *githubtest.Server, dir string, extra ...string) []string {
+	args := []string{"import",
+		"--source", "api",
+		"--gitlab-url", gl.URL,
+		"--gitlab-token", "gitlab-token",
+		"--project", "group/project",
+		"--repo", testRepo,
+		"--token", "test-token",
+		"--api-url", srv.URL,
+		"--mapping", filepath.Join(dir, "mapping.json"),
+	}
+	return append(args, extra...)
+}
+
+func Test_Import_Links(t *testing.T) {
+	gl := linkedProject(t)
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	assert.Nil(t, execute(linkedArgs(gl, srv, dir)...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 4) {
+		assert.True(t, strings.HasSuffix(repo.Issues[0].Body, "### Related issues\n\n- relates to #2\n- blocks #4"))
+		assert.Contains(t, repo.Issues[1].Body, "- relates to gitlab issue `group/other#7` (not migrated)")
+		assert.NotContains(t, repo.Issues[2].Body, "Related issues")
+		assert.Contains(t, repo.Issues[3].Body, "- is blocked by #1")
+		// the dependency is added once, although both issues list it
+		assert.Equal(t, []int64{repo.Issues[0].Id}, repo.Issues[3].BlockedBy)
+	}
+
+	// the section survives updates and re-runs
+	first := repo.Issues[0].Body
+	assert.Nil(t, execute(linkedArgs(gl, srv, dir, "--existing", "update")...))
+	assert.Equal(t, first, repo.Issues[0].Body)
+
+	// the dependencies are added even if the related issues are not listed
+	srv = githubtest.NewServer()
+	defer srv.Close()
+	assert.Nil(t, execute(linkedArgs(gl, srv, t.TempDir(), "--links", "none")...))
+	repo = srv.Repo(testRepo)
+	assert.NotContains(t, repo.Issues[0].Body, "Related issues")
+	assert.Equal(t, []int64{repo.Issues[0].Id}, repo.Issues[3].BlockedBy)
+
+	// or the related issues can be listed in comments
+	srv = githubtest.NewServer()
+	defer srv.Close()
+	assert.Nil(t, execute(linkedArgs(gl, srv, t.TempDir(), "--links", "comment")...))
+	repo = srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues[0].Comments, 1) {
+		assert.Contains(t, repo.Issues[0].Comments[0].Body, "- blocks #4")
+	}
+	assert.NotContains(t, repo.Issues[0].Body, "Related issues")
+
+	// servers without dependencies still get the related issues
+	srv = githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Path: "/repos/" + testRepo + "/issues/4/dependencies", Status: http.StatusNotFound})
+	assert.Nil(t, execute(linkedArgs(gl, srv, t.TempDir())...))
+	repo = srv.Repo(testRepo)
+	assert.Empty(t, repo.Issues[3].BlockedBy)
+	assert.Contains(t, repo.Issues[3].Body, "- is blocked by #1")
+
+	// but invalid dependencies (e.g. cycles) are errors
+	srv = githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Method: http.MethodPost, Path: "/repos/" + testRepo + "/issues/4/dependencies", Status: http.StatusUnprocessableEntity})
+	assert.NotNil(t, execute(linkedArgs(gl, srv, t.TempDir())...))
+}
+
+func Test_Import_Tasks(t *testing.T) {
+	gl := linkedProject(t)
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	// issue #4 is a task of issue #1
+	assert.Nil(t, execute(linkedArgs(gl, srv, dir)...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 4) {
+		assert.Equal(t, []int64{repo.Issues[3].Id}, repo.Issues[0].SubIssues)
+		assert.NotContains(t, repo.Issues[0].Body, "### Tasks")
+	}
+	assert.Nil(t, execute(linkedArgs(gl, srv, dir)...))
+	assert.Len(t, repo.Issues[0].SubIssues, 1)
+
+	// the tasks are listed in the parent issue if github does not support sub-issues
+	for _, args := range [][]string{{"--tasks", "list"}, {}} {
+		srv := githubtest.NewServer()
+		defer srv.Close()
+		srv.Fail(githubtest.Fault{Path: "/repos/" + testRepo + "/issues/1/sub_issues", Status: http.StatusNotFound})
+		assert.Nil(t, execute(linkedArgs(gl, srv, t.TempDir(), args...)...))
+		repo := srv.Repo(testRepo)
+		assert.Empty(t, repo.Issues[0].SubIssues)
+		assert.True(t, strings.HasSuffix(repo.Issues[0].Body, "- blocks #4\n\n### Tasks\n\n- [ ] #4"))
+	}
+
+	// a task that cannot be added (e.g. it already has another parent) is an error
+	srv = githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Method: http.MethodPost, Path: "/repos/" + testRepo + "/issues/1/sub_issues", Status: http.StatusUnprocessableEntity})
+	assert.NotNil(t, execute(linkedArgs(gl, srv, t.TempDir())...))
+}
+
+func Test_Import_Faults(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	srv.Fail(githubtest.Fault{Method: "POST", Path: "/repos/" + testRepo + "/issues", Status: 502, Times: 1})
+	err := execute(importArgs(srv, dir)...)
+	assert.Equal(t, ExitAPI, ExitCode(err))
+	report := readReport(t, dir)
+	assert.NotEmpty(t, report.Error)
+	assert.Equal(t, migrate.OutcomeFailed, report.Issues[0].Outcome)
+
+	// the run can be resumed
+	assert.Nil(t, execute(importArgs(srv, dir)...))
+	assert.Len(t, srv.Repo(testRepo).Issues, 4)
+
+	srv.Fail(githubtest.Fault{Path: "/repos/", Status: 401})
+	err = execute(importArgs(srv, dir, "--comments")...)
+	assert.Equal(t, ExitAuth, ExitCode(err))
+}
+
+func Test_Import_ConfigErrors(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	err := execute("import", "--export", "testdata/issues.ndjson", "--repo", testRepo, "--token", "x",
+		"--api-url", srv.URL, "--comments")
+	assert.Equal(t, ExitConfig, ExitCode(err))
+
+	err = execute("import", "--export", "testdata/missing.ndjson", "--repo", testRepo, "--token", "x", "--api-url", srv.URL)
+	assert.Equal(t, ExitParse, ExitCode(err))
+}
+
+func Test_Import_RecordReplay(t *testing.T) {
+	srv := githubtest.NewServer()
+	dir, replayDir := t.TempDir(), t.TempDir()
+	cassette := filepath.Join(dir, "cassette.jsonl")
+
+	assert.Nil(t, execute(importArgs(srv, dir, "--record", cassette)...))
+	srv.Close()
+	data, err := os.ReadFile(cassette)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "test-token")
+
+	// the same run is served from the cassette
+	args := importArgs(srv, replayDir, "--replay", cassette)
+	assert.Nil(t, execute(args...))
+	assert.Equal(t, []int{1, 2, 3, 4}, readReport(t, replayDir).Created)
+
+	// a run that diverges from the recording fails
+	assert.NotNil(t, execute(append(args, "--comments")...))
+}
+
+func Test_Import_DryRun(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	srv.AddIssue(testRepo, "an unrelated issue", "")
+
+	assert.Nil(t, execute(importArgs(srv, dir, "--dry-run")...))
+	assert.Len(t, srv.Repo(testRepo).Issues, 1)
+	// the simulated numbers follow the issue that already exists
+	assert.Equal(t, []int{2, 3, 4, 5}, readReport(t, dir).Created)
+	_, err := os.Stat(filepath.Join(dir, "mapping.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func Test_Import_Gitea(t *testing.T) {
+	srv := giteatest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	args := []string{"import",
+		"--export", "testdata/issues.ndjson",
+		"--repo", testRepo,
+		"--target", "gitea",
+		"--url", srv.URL,
+		"--token", "test-token",
+		"--mapping", filepath.Join(dir, "mapping.json"),
+	}
+	assert.Nil(t, execute(args...))
+	assert.Nil(t, execute(append(args, "--comments")...))
+	assert.Nil(t, execute(args...))
+
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 4) {
+		assert.Equal(t, "open", repo.Issues[0].State)
+		// closed issues are created closed and labelled like on github
+		assert.Equal(t, "closed", repo.Issues[1].State)
+		assert.Equal(t, []string{"closed"}, repo.Issues[1].Labels)
+		assert.Empty(t, repo.Issues[0].Labels)
+		assert.Len(t, repo.Issues[0].Comments, 2)
+	}
+
+	assert.NotNil(t, execute("import", "--export", "testdata/issues.ndjson", "--repo", testRepo, "--target", "gitea", "--token", "t"))
+	assert.NotNil(t, execute("rate", "--target", "gitea", "--url", srv.URL, "--token", "t"))
+}
+
+func Test_Import_GitlabAPI(t *testing.T) {
+	gl := gitlabtest.NewServer()
+	defer gl.Close()
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	issue := gl.AddIssue("group/project", "First issue", "a description")
+	gl.AddNote(issue, "A first comment")
+	gl.AddIssue("group/project", "Second issue", "")
+
+	args := []string{"import",
+		"--source", "api",
+		"--gitlab-url", gl.URL,
+		"--gitlab-token", "gitlab-token",
+		"--project", "group/project",
+		"--repo", testRepo,
+		"--token", "test-token",
+		"--api-url", srv.URL,
+		"--mapping", filepath.Join(t.TempDir(), "mapping.json"),
+	}
+	assert.Nil(t, execute(args...))
+	assert.Nil(t, execute(append(args, "--comments")...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 2) {
+		assert.Equal(t, "First issue", repo.Issues[0].Title)
+		assert.Len(t, repo.Issues[0].Comments, 1)
+	}
+
+	// the export is required unless the source is the API
+	err := execute("import", "--repo", testRepo, "--token", "test-token", "--api-url", srv.URL)
+	assert.Equal(t, ExitConfig, ExitCode(err))
+	t.Setenv("GITLAB_TOKEN", "")
+	err = execute("summary", "--source", "api", "--gitlab-url", gl.URL, "--project", "group/project")
+	assert.Equal(t, ExitConfig, ExitCode(err))
+}
@@ -13,7 +13,7 @@ func PostCommand(globals *GlobalVariables) *cobra.Command {
 	var (
 		issueId uint
 		repo    string
-		token   string
+		auth    AuthVariables
 		delay   time.Duration
 		labels  []string
 		dryRun  bool
@@ -23,14 +23,15 @@ func PostCommand(globals *GlobalVariables) *cobra.Command {
 			Use:   "post",
 			Short: descr,
 			Long:  descr,
-			Run: func(cmd *cobra.Command, args []string) {
-				mappings := ReverseMapping(globals.UserMappings)
+			RunE: func(cmd *cobra.Command, args []string) error {
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
 
-				// parse ndjson
-				issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+				issues, err := globals.Issues(cmd.Context())
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "error: %v\n", err)
-					return
+					return err
 				}
 
 				// find the issue
@@ -42,31 +43,34 @@ func PostCommand(globals *GlobalVariables) *cobra.Command {
 					}
 				}
 				if issue == nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "Issue with id=%d was not found in export file\n", issueId)
-					return
+					return configError(fmt.Errorf("issue with id=%d was not found in %s", issueId, globals.SourceName()))
 				}
 
 				// ok, let's now post the issue
-				client := github.NewClient(token, dryRun, globals.Debug)
-				ghIssue, err := github.New(issue, mappings, labels, globals.ReplacePatterns)
+				target, err := auth.NewTarget(dryRun, globals.Debug, delay)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
+				ghIssue, err := github.New(issue, labels, opts)
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "Preparation error: %v\n", err)
+					return parseError(fmt.Errorf("preparation error: %v", err))
 				}
-				if err := ghIssue.Post(client, repo); err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "Posting error: %v\n", err)
+				if err := ghIssue.Post(cmd.Context(), target, repo); err != nil {
+					return fmt.Errorf("posting error: %w", err)
 				}
+				return nil
 			},
 		}
 	)
 
 	cmd.Flags().UintVar(&issueId, "id", 0, "the ID of the issue to be displated")
 	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "the API token for authenticating with github API")
-	cmd.Flags().DurationVar(&delay, "delay", time.Duration(10*time.Millisecond), "delay between successive API calls")
+	authFlags(cmd, &auth)
+	cmd.Flags().DurationVar(&delay, "delay", 0, "minimum delay between successive API calls that create content (requests are paced according to github's rate limits anyway)")
 	cmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "a comma-separated list of labels to be attached to the issue")
-	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "if true then no API call will be made")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "simulate the changes without making them in github (the state of the repo is still read)")
 	cmd.MarkFlagRequired("id")
 	cmd.MarkFlagRequired("repo")
-	cmd.MarkFlagRequired("token")
-	return requireGlobalFlags(cmd, globals, []string{"export"})
+	return requireGlobalFlags(cmd, globals, []string{})
 }
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/spf13/cobra"
+)
+
+func RedirectSourceCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		startFromId int
+		endAtId     int
+		repo        string
+		githubURL   string
+		closeIssues bool
+		lock        bool
+		delay       time.Duration
+		dryRun      bool
+		mappingPath string
+		reportPath  string
+
+		descr = "Point the migrated gitlab issues to their github counterparts"
+		cmd   = &cobra.Command{
+			Use:   "redirect-source",
+			Short: descr,
+			Long: descr + ": add a note to every migrated gitlab issue (as recorded in the mapping) " +
+				"that links to the github issue and optionally close the gitlab issue and lock its discussion. " +
+				"Issues that have already been redirected do not get a second note.",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				client, err := globals.GitlabClient(gitlab.WithWriteDelay(delay))
+				if err != nil {
+					return err
+				}
+				mapping, err := migrate.LoadMapping(mappingPath)
+				if err != nil {
+					return configError(err)
+				}
+				prefix := ""
+				if dryRun {
+					prefix = "[dry-run] "
+				}
+
+				ctx, stop := interruptible(cmd.Context())
+				defer stop()
+				report, err := migrate.RedirectSource(ctx, client, migrate.RedirectOptions{
+					Project:   globals.Project,
+					Repo:      repo,
+					GithubURL: githubURL,
+					Start:     startFromId,
+					End:       endAtId,
+					Close:     closeIssues,
+					Lock:      lock,
+					DryRun:    dryRun,
+					Mapping:   mapping,
+					Redirected: func(iid, number int, actions []string) {
+						log.Printf("%s[#%d] redirected to #%d (%s)", prefix, iid, number, strings.Join(actions, ", "))
+					},
+				})
+
+				log.Printf("Summary:\n%s", report.Summarize())
+				if reportPath != "" {
+					if werr := report.Write(reportPath); werr != nil {
+						log.Printf("error: %v", werr)
+					}
+				}
+				return err
+			},
+		}
+	)
+
+	cmd.Flags().IntVar(&startFromId, "start", 1, "ID to start from (lower IDs will be skipped)")
+	cmd.Flags().IntVar(&endAtId, "end", 0, "ID to stop at (inclusive)")
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the github repo of the migrated issues in the form 'user_or_org/repo_name'")
+	cmd.Flags().StringVar(&githubURL, "github-url", "https://github.com", "the web URL of github (for links to the migrated issues)")
+	cmd.Flags().BoolVar(&closeIssues, "close", false, "close the gitlab issues")
+	cmd.Flags().BoolVar(&lock, "lock", false, "lock the discussions of the gitlab issues")
+	cmd.Flags().DurationVar(&delay, "delay", time.Second, "minimum delay between successive gitlab API calls that make changes")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report the changes without making them in gitlab (the notes of the issues are still read)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the migrated gitlab issues")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write a JSON report of the run to the specified file")
+	cmd.MarkFlagRequired("repo")
+	cmd.MarkFlagRequired("mapping")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/gitlab/gitlabtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RedirectSource(t *testing.T) {
+	gl := gitlabtest.NewServer()
+	defer gl.Close()
+	dir := t.TempDir()
+	t.Setenv("GITLAB_TOKEN", "")
+
+	first := gl.AddIssue("group/project", "First issue", "")
+	second := gl.AddIssue("group/project", "Second issue", "")
+	// issue #3 was deleted from gitlab (and migrated as a placeholder)
+	mappingPath := filepath.Join(dir, "mapping.json")
+	assert.Nil(t, os.WriteFile(mappingPath, []byte(`{"issues":{"1":5,"2":6,"3":7}}`), 0644))
+
+	args := func(extra ...string) []string {
+		return append([]string{"redirect-source",
+			"--gitlab-url", gl.URL,
+			"--gitlab-token", "gitlab-token",
+			"--project", "group/project",
+			"--repo", testRepo,
+			"--mapping", mappingPath,
+			"--report", filepath.Join(dir, "report.json"),
+			"--delay", "0",
+		}, extra...)
+	}
+
+	assert.Nil(t, execute(args("--close", "--lock", "--dry-run")...))
+	assert.Empty(t, first.Notes)
+	assert.Equal(t, "opened", first.State)
+	assert.Equal(t, "changed", readReport(t, dir).Issues[0].Outcome)
+
+	assert.Nil(t, execute(args("--close", "--lock")...))
+	for i, issue := range []*gitlabtest.Issue{first, second} {
+		if assert.Len(t, issue.Notes, 1) {
+			assert.Contains(t, issue.Notes[0].Body, fmt.Sprintf("https://github.com/%s/issues/%d", testRepo, 5+i))
+		}
+		assert.Equal(t, "closed", issue.State)
+		assert.True(t, issue.DiscussionLocked)
+	}
+	report := readReport(t, dir)
+	if assert.Len(t, report.Issues, 3) {
+		assert.Equal(t, "updated", report.Issues[0].Outcome)
+		assert.Equal(t, "skipped", report.Issues[2].Outcome)
+	}
+
+	// the issues are not noted, closed or locked twice
+	requests := gl.Requests()
+	assert.Nil(t, execute(args("--close", "--lock")...))
+	assert.Len(t, first.Notes, 1)
+	assert.Equal(t, "unchanged", readReport(t, dir).Issues[0].Outcome)
+	// (only reads: each issue and its notes, and the missing #3)
+	assert.Equal(t, requests+5, gl.Requests())
+}
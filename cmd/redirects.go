@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/spf13/cobra"
+)
+
+func RedirectsCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		repo        string
+		githubURL   string
+		format      string
+		output      string
+		mappingPath string
+
+		descr = "Generate redirects from the URLs of the migrated gitlab issues to the github issues"
+		cmd   = &cobra.Command{
+			Use:   "redirects",
+			Short: descr,
+			Long: descr + fmt.Sprintf(" in one of the formats %v. ", migrate.RedirectFormats) +
+				"Both the /issues/N and /-/issues/N forms are covered. Links to notes (#note_N) are redirected " +
+				"to their comments by the html pages (and listed in the csv) when the issues are available " +
+				"through --export or --source api; web servers never see anchors, so their redirects lead to the issue.",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if globals.Project == "" {
+					return configError(fmt.Errorf("--project is required for the paths of the gitlab issues"))
+				}
+				if !contains(migrate.RedirectFormats, format) {
+					return configError(fmt.Errorf("unknown redirect format %q (one of: %v)", format, migrate.RedirectFormats))
+				}
+				if format == migrate.RedirectsHTML && output == "" {
+					return configError(fmt.Errorf("--output (a directory) is required for the html format"))
+				}
+				mapping, err := migrate.LoadMapping(mappingPath)
+				if err != nil {
+					return configError(err)
+				}
+				var issues []*gitlab.Issue
+				if globals.HasSource() {
+					if issues, err = globals.Issues(cmd.Context()); err != nil {
+						return err
+					}
+				}
+				redirects := migrate.NewRedirects(mapping, issues, globals.Project, githubURL, repo)
+
+				if format == migrate.RedirectsHTML {
+					err = redirects.WriteHTML(output)
+				} else {
+					var w io.Writer = os.Stdout
+					if output != "" {
+						f, err := os.Create(output)
+						if err != nil {
+							return fmt.Errorf("failed to create %s: %v", output, err)
+						}
+						defer f.Close()
+						w = f
+					}
+					err = redirects.Write(w, format)
+				}
+				if err != nil {
+					return err
+				}
+				if output != "" {
+					log.Printf("%d issue redirects written to %s", len(redirects.Redirects), output)
+				}
+				return nil
+			},
+		}
+	)
+
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the github repo of the migrated issues in the form 'user_or_org/repo_name'")
+	cmd.Flags().StringVar(&githubURL, "github-url", "https://github.com", "the web URL of github")
+	cmd.Flags().StringVar(&format, "format", migrate.RedirectsNginx, fmt.Sprintf("the format of the redirects (one of: %v)", migrate.RedirectFormats))
+	cmd.Flags().StringVarP(&output, "output", "o", "", "the file to write to (a directory for the html format; stdout if omitted)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the migrated gitlab issues")
+	cmd.MarkFlagRequired("repo")
+	cmd.MarkFlagRequired("mapping")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
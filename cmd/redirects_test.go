@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Redirects(t *testing.T) {
+	dir := t.TempDir()
+	mappingPath := filepath.Join(dir, "mapping.json")
+	assert.Nil(t, os.WriteFile(mappingPath, []byte(`{"issues":{"1":11,"2":12,"4":14},"notes":{"101":555}}`), 0644))
+	args := func(format, output string) []string {
+		return []string{"redirects",
+			"--export", "testdata/issues.ndjson",
+			"--project", "group/project",
+			"--repo", testRepo,
+			"--mapping", mappingPath,
+			"--format", format,
+			"--output", filepath.Join(dir, output),
+		}
+	}
+
+	assert.Nil(t, execute(args("nginx", "redirects.conf")...))
+	data, err := os.ReadFile(filepath.Join(dir, "redirects.conf"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "map $uri $gitlab_issue_redirect {")
+	assert.Contains(t, string(data), "    /group/project/issues/1 https://github.com/"+testRepo+"/issues/11;\n")
+	assert.Contains(t, string(data), "    /group/project/-/issues/4 https://github.com/"+testRepo+"/issues/14;\n")
+
+	assert.Nil(t, execute(args("csv", "redirects.csv")...))
+	data, err = os.ReadFile(filepath.Join(dir, "redirects.csv"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "/group/project/-/issues/1#note_101,https://github.com/"+testRepo+"/issues/11#issuecomment-555\n")
+	assert.Contains(t, string(data), "/group/project/issues/2,https://github.com/"+testRepo+"/issues/12\n")
+
+	assert.Nil(t, execute(args("html", "pages")...))
+	data, err = os.ReadFile(filepath.Join(dir, "pages", "group", "project", "-", "issues", "1", "index.html"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `content="0; url=https://github.com/`+testRepo+`/issues/11"`)
+	assert.Contains(t, string(data), `var notes = {"101":555};`)
+	assert.FileExists(t, filepath.Join(dir, "pages", "group", "project", "issues", "4", "index.html"))
+
+	assert.NotNil(t, execute(args("yaml", "redirects.yaml")...))
+}
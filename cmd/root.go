@@ -1,15 +1,105 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/spf13/cobra"
+)
+
+// the sources of gitlab issues
+const (
+	sourceExport = "export"
+	sourceAPI    = "api"
+)
+
+// environment variable that is checked for a gitlab token
+const gitlabTokenEnvVar = "GITLAB_TOKEN"
 
 type GlobalVariables struct {
+	Source                 string
 	ExportPath             string
+	GitlabURL              string
+	GitlabToken            string
+	Project                string
 	CommentExclusionFilter []string
 	UserMappings           map[string]int
 	ReplacePatterns        map[string]string
+	Attribution            string
+	Sanitize               bool
 	Debug                  bool
 }
 
+// RenderOptions returns the options for converting gitlab issues and comments
+func (globals *GlobalVariables) RenderOptions() (gitlab.Options, error) {
+	attribution, err := gitlab.ParseAttribution(globals.Attribution)
+	if err != nil {
+		return gitlab.Options{}, err
+	}
+	return gitlab.Options{
+		Project:         globals.Project,
+		GitlabURL:       globals.GitlabURL,
+		Mappings:        ReverseMapping(globals.UserMappings),
+		ReplacePatterns: globals.ReplacePatterns,
+		Attribution:     attribution,
+		Sanitize:        globals.Sanitize,
+	}, nil
+}
+
+// Issues returns the gitlab issues from the export or the gitlab API (depending on the source)
+func (globals *GlobalVariables) Issues(ctx context.Context) ([]*gitlab.Issue, error) {
+	switch globals.Source {
+	case sourceExport:
+		if globals.ExportPath == "" {
+			return nil, configError(fmt.Errorf("--export is required (or use --source %s)", sourceAPI))
+		}
+		issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+		if err != nil {
+			return nil, parseError(err)
+		}
+		return issues, nil
+	case sourceAPI:
+		client, err := globals.GitlabClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.Issues(ctx, globals.Project, globals.CommentExclusionFilter)
+	default:
+		return nil, configError(fmt.Errorf("unknown source: %s (expected %s or %s)", globals.Source, sourceExport, sourceAPI))
+	}
+}
+
+// HasSource returns true if a source of gitlab issues has been specified
+func (globals *GlobalVariables) HasSource() bool {
+	return globals.Source == sourceAPI || globals.ExportPath != ""
+}
+
+// SourceName describes the source of the gitlab issues
+func (globals *GlobalVariables) SourceName() string {
+	if globals.Source == sourceAPI {
+		return fmt.Sprintf("project %s at %s", globals.Project, globals.GitlabURL)
+	}
+	return "file " + globals.ExportPath
+}
+
+// GitlabClient returns a client for the gitlab API of the project
+func (globals *GlobalVariables) GitlabClient(opts ...gitlab.APIOption) (*gitlab.APIClient, error) {
+	if globals.GitlabURL == "" || globals.Project == "" {
+		return nil, configError(fmt.Errorf("--gitlab-url and --project are required for the gitlab API"))
+	}
+	token := globals.GitlabToken
+	if token == "" {
+		token = strings.TrimSpace(os.Getenv(gitlabTokenEnvVar))
+	}
+	if token == "" {
+		return nil, configError(fmt.Errorf("no gitlab token found (use --gitlab-token or %s)", gitlabTokenEnvVar))
+	}
+	return gitlab.NewAPIClient(globals.GitlabURL, token, globals.Debug, opts...), nil
+}
+
 var DefaultCommentExclusionFilter = []string{
 	"mentioned in",
 	"assigned to",
@@ -38,6 +128,23 @@ func New() *cobra.Command {
 		Use:   "gl2gh",
 		Short: descr,
 		Long:  descr,
+		// errors are reported by the caller of Execute()
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return configError(err)
+	})
+	// cobra validates the required flags (and flag groups) after the pre-run hooks
+	// without going through the FlagErrorFunc, so they are validated here first
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return configError(err)
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return configError(err)
+		}
+		return nil
 	}
 
 	root.AddCommand(SummaryCommand(globals))
@@ -45,17 +152,30 @@ func New() *cobra.Command {
 	root.AddCommand(UsersCommand(globals))
 	root.AddCommand(PostCommand(globals))
 	root.AddCommand(ImportCommand(globals))
+	root.AddCommand(UpdateCommand(globals))
+	root.AddCommand(SyncCommand(globals))
+	root.AddCommand(ServeCommand(globals))
+	root.AddCommand(RedirectSourceCommand(globals))
+	root.AddCommand(RedirectsCommand(globals))
 	root.AddCommand(RateCommand(globals))
+	root.AddCommand(DoctorCommand(globals))
 
 	return root
 }
 
 func requireGlobalFlags(cmd *cobra.Command, globals *GlobalVariables, require []string) *cobra.Command {
+	cmd.Flags().StringVar(&globals.Source, "source", sourceExport, "where the gitlab issues are read from (export or api)")
 	cmd.Flags().StringVarP(&globals.ExportPath, "export", "e", "", "directory that contains the uncompressed gitlab export")
+	cmd.Flags().StringVar(&globals.Project, "project", "", "the path of the gitlab project (e.g. group/project)")
+	cmd.Flags().StringVar(&globals.GitlabURL, "gitlab-url", "", "the URL of the gitlab instance (e.g. https://gitlab.com) for the API source and for links to the original issues and notes (with --project)")
+	cmd.Flags().StringVar(&globals.GitlabToken, "gitlab-token", "", fmt.Sprintf("the API token for the gitlab API (also looked up in %s)", gitlabTokenEnvVar))
 	cmd.Flags().StringSliceVarP(&globals.CommentExclusionFilter, "filter", "f", DefaultCommentExclusionFilter, "exclude comments that start with the supplied substrings")
 	cmd.Flags().StringToIntVarP(&globals.UserMappings, "users", "u", map[string]int{}, "mapping of github user names to gitlab UIDs")
 	cmd.Flags().StringToStringVar(&globals.ReplacePatterns, "replace", map[string]string{},
 		"specify pairs of replacement patterns for issue and comment texts (useful for replacing link URIs)")
+	cmd.Flags().StringVar(&globals.Attribution, "attribution", string(gitlab.AttributionMention),
+		"how mapped issue authors are referenced (mention, code, link or deferred)")
+	cmd.Flags().BoolVar(&globals.Sanitize, "sanitize", false, "neutralise @mentions and #123 references in issue and comment texts")
 	cmd.Flags().BoolVarP(&globals.Debug, "debug", "d", false, "whether to display debugging information")
 	for _, req := range require {
 		cmd.MarkFlagRequired(req)
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/kkentzo/gl-to-gh/webhook"
+	"github.com/spf13/cobra"
+)
+
+// environment variable that is checked for the webhook secret token
+const webhookSecretEnvVar = "GITLAB_WEBHOOK_SECRET"
+
+func ServeCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		listen      string
+		secret      string
+		queueDir    string
+		repo        string
+		auth        AuthVariables
+		labels      []string
+		delay       time.Duration
+		retry       time.Duration
+		dryRun      bool
+		mappingPath string
+
+		descr = "Mirror gitlab issue events to github in real time"
+		cmd   = &cobra.Command{
+			Use:   "serve",
+			Short: descr,
+			Long: descr + ": receive gitlab's Issue Hook and Note Hook webhooks and create or update " +
+				"the corresponding github issues and comments. Received events are stored in a queue " +
+				"directory until they are applied, so that no event is lost across restarts or github outages.",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if secret == "" {
+					secret = strings.TrimSpace(os.Getenv(webhookSecretEnvVar))
+				}
+				if secret == "" {
+					return configError(fmt.Errorf("no webhook secret found (use --secret or %s)", webhookSecretEnvVar))
+				}
+
+				target, err := auth.NewTarget(dryRun, globals.Debug, delay)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
+				mapping, err := migrate.LoadMapping(mappingPath)
+				if err != nil {
+					return configError(err)
+				}
+				if dryRun {
+					mapping.Discard()
+				}
+				queue, err := webhook.OpenQueue(queueDir)
+				if err != nil {
+					return configError(err)
+				}
+				if dryRun {
+					// the events are applied to the simulator only, so keep them for the real run
+					queue.Preserve()
+				}
+				if pending, err := queue.Len(); err == nil && pending > 0 {
+					log.Printf("%d queued events will be applied", pending)
+				}
+
+				mirror := webhook.NewMirror(target, webhook.Options{
+					Repo:                   repo,
+					Labels:                 labels,
+					Render:                 opts,
+					CommentExclusionFilter: globals.CommentExclusionFilter,
+					Mapping:                mapping,
+				})
+
+				ctx, stop := interruptible(cmd.Context())
+				defer stop()
+
+				server := &http.Server{
+					Addr:              listen,
+					Handler:           webhook.NewHandler(queue, secret),
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				serverErr := make(chan error, 1)
+				go func() {
+					log.Printf("listening for gitlab webhooks on %s", listen)
+					serverErr <- server.ListenAndServe()
+				}()
+				workerErr := make(chan error, 1)
+				go func() {
+					workerErr <- webhook.Run(ctx, queue, mirror, retry)
+				}()
+
+				select {
+				case err = <-serverErr:
+					stop()
+					<-workerErr
+				case err = <-workerErr:
+				case <-ctx.Done():
+					err = <-workerErr
+				}
+				shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				server.Shutdown(shutdown)
+				if errors.Is(err, http.ErrServerClosed) {
+					return nil
+				}
+				return err
+			},
+		}
+	)
+
+	cmd.Flags().StringVar(&listen, "listen", ":8080", "the address to listen on for webhooks")
+	cmd.Flags().StringVar(&secret, "secret", "", fmt.Sprintf("the secret token of the gitlab webhook (or set %s)", webhookSecretEnvVar))
+	cmd.Flags().StringVar(&queueDir, "queue", "", "directory that stores the received events until they are applied")
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
+	authFlags(cmd, &auth)
+	cmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "a comma-separated list of labels to be attached to new issues")
+	cmd.Flags().DurationVar(&delay, "delay", 0, "minimum delay between successive API calls that create content (requests are paced according to github's rate limits anyway)")
+	cmd.Flags().DurationVar(&retry, "retry", 30*time.Second, "delay before retrying an event that could not be applied")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "simulate the changes without making them in github (the state of the repo is still read and the queued events are kept)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the mirrored gitlab issues")
+	cmd.MarkFlagRequired("queue")
+	cmd.MarkFlagRequired("repo")
+	cmd.MarkFlagRequired("mapping")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
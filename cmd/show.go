@@ -16,14 +16,15 @@ func ShowCommand(globals *GlobalVariables) *cobra.Command {
 			Use:   "show",
 			Short: descr,
 			Long:  descr,
-			Run: func(cmd *cobra.Command, args []string) {
-				mappings := ReverseMapping(globals.UserMappings)
+			RunE: func(cmd *cobra.Command, args []string) error {
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
 
-				// parse ndjson
-				issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+				issues, err := globals.Issues(cmd.Context())
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "error: %v\n", err)
-					return
+					return err
 				}
 
 				// find issue
@@ -35,34 +36,32 @@ func ShowCommand(globals *GlobalVariables) *cobra.Command {
 					}
 				}
 				if issue == nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "Issue with id=%d was not found in export file\n", issueId)
-					return
+					return configError(fmt.Errorf("issue with id=%d was not found in %s", issueId, globals.SourceName()))
 				}
 
 				fmt.Println(issue.Summarize())
 
-				s, err := issue.Convert(mappings, globals.ReplacePatterns)
+				s, err := issue.Convert(opts)
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "Error converting issue %d: %v", issueId, err)
-					return
+					return parseError(fmt.Errorf("error converting issue %d: %v", issueId, err))
 				}
 				fmt.Println(s)
 
 				for _, comment := range issue.Comments {
-					s, err := comment.Convert(globals.ReplacePatterns)
+					s, err := comment.Convert(opts)
 					if err != nil {
-						fmt.Fprintf(cmd.OutOrStderr(), "Error converting comment for issue %d: %v", issueId, err)
-						return
+						return parseError(fmt.Errorf("error converting comment for issue %d: %v", issueId, err))
 					}
 
 					fmt.Println(s)
 					fmt.Println("=============================================")
 				}
+				return nil
 			},
 		}
 	)
 
 	cmd.Flags().UintVar(&issueId, "id", 0, "the ID of the issue to be displated")
 	cmd.MarkFlagRequired("id")
-	return requireGlobalFlags(cmd, globals, []string{"export"})
+	return requireGlobalFlags(cmd, globals, []string{})
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptible returns a context that is cancelled on the first SIGINT/SIGTERM,
+// which allows the current operation to complete before stopping.
+// A second signal terminates the process immediately.
+func interruptible(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigs:
+			log.Printf("interrupted: stopping after the current item (interrupt again to abort immediately)")
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case <-sigs:
+			log.Printf("aborted")
+			os.Exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		close(done)
+		cancel()
+	}
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/kkentzo/gl-to-gh/gitlab"
 	"github.com/spf13/cobra"
 )
 
@@ -14,11 +13,10 @@ func SummaryCommand(globals *GlobalVariables) *cobra.Command {
 			Use:   "summary",
 			Short: descr,
 			Long:  descr,
-			Run: func(cmd *cobra.Command, args []string) {
-				// parse ndjson
-				issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+			RunE: func(cmd *cobra.Command, args []string) error {
+				issues, err := globals.Issues(cmd.Context())
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "error: %v\n", err)
+					return err
 				}
 
 				nc := 0
@@ -30,9 +28,10 @@ func SummaryCommand(globals *GlobalVariables) *cobra.Command {
 
 				fmt.Fprintf(cmd.OutOrStderr(), "Issues: %d\n", len(issues))
 				fmt.Fprintf(cmd.OutOrStderr(), "Comments: %d\n", nc)
+				return nil
 			},
 		}
 	)
 
-	return requireGlobalFlags(cmd, globals, []string{"export"})
+	return requireGlobalFlags(cmd, globals, []string{})
 }
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/spf13/cobra"
+)
+
+func SyncCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		startFromId int
+		endAtId     int
+		repo        string
+		auth        AuthVariables
+		labels      []string
+		delay       time.Duration
+		dryRun      bool
+		mappingPath string
+		statePath   string
+		reportPath  string
+		links       string
+		tasks       string
+
+		descr = "Sync the issues that were created or changed in gitlab since the last run"
+		cmd   = &cobra.Command{
+			Use:   "sync",
+			Short: descr,
+			Long: descr + ": new issues are created, new notes are appended as comments and " +
+				"title edits and state changes (closed/reopened) are propagated to the migrated issues",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				target, err := auth.NewTarget(dryRun, globals.Debug, delay)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
+
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
+
+				issues, err := globals.Issues(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				mapping, err := migrate.LoadMapping(mappingPath)
+				if err != nil {
+					return configError(err)
+				}
+				state, err := migrate.LoadSyncState(statePath)
+				if err != nil {
+					return configError(err)
+				}
+				if dryRun {
+					mapping.Discard()
+					state.Discard()
+				}
+
+				migrator, err := migrate.New(target, issues, migrate.Options{
+					Repo:    repo,
+					Labels:  labels,
+					Start:   startFromId,
+					End:     endAtId,
+					Render:  opts,
+					Mapping: mapping,
+					Links:   links,
+					Tasks:   tasks,
+					Events: migrate.Events{
+						IssueCreated: func(iid int, issue *github.Issue) {
+							log.Printf("[#%d] %s (created as #%d)", iid, issue.Title, issue.Number)
+						},
+						PlaceholderCreated: func(iid int, issue *github.Issue) {
+							log.Printf("[#%d] %s (placeholder)", iid, issue.Title)
+						},
+						IssueSynced: func(iid int, number int, changes []string) {
+							log.Printf("[#%d] issue #%d synced (%s)", iid, number, strings.Join(changes, ", "))
+						},
+					},
+				})
+				if err != nil {
+					return configError(err)
+				}
+
+				ctx, stop := interruptible(cmd.Context())
+				defer stop()
+				err = migrator.Sync(ctx, state)
+
+				report := migrator.Report()
+				log.Printf("Summary:\n%s", report.Summarize())
+				if reportPath != "" {
+					if werr := report.Write(reportPath); werr != nil {
+						log.Printf("error: %v", werr)
+					}
+				}
+				return err
+			},
+		}
+	)
+
+	cmd.Flags().IntVar(&startFromId, "start", 1, "ID to start the sync from (lower IDs will be skipped)")
+	cmd.Flags().IntVar(&endAtId, "end", 0, "ID to stop the sync at (inclusive)")
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
+	authFlags(cmd, &auth)
+	cmd.Flags().StringSliceVarP(&labels, "labels", "l", []string{}, "a comma-separated list of labels to be attached to new issues")
+	cmd.Flags().DurationVar(&delay, "delay", 0, "minimum delay between successive API calls that create content (requests are paced according to github's rate limits anyway)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "simulate the changes without making them in github (the state of the repo is still read)")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the migrated gitlab issues")
+	cmd.Flags().StringVar(&statePath, "state", "", "file that records the last sync point of every issue")
+	cmd.Flags().StringVar(&links, "links", migrate.LinksBody, "where the related (linked) issues are listed: body, comment or none (dependencies are added where github supports them)")
+	cmd.Flags().StringVar(&tasks, "tasks", migrate.TasksSubIssues, "how child tasks are migrated: sub-issues or list (API source only)")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write a JSON report of the run to the specified file")
+	cmd.MarkFlagRequired("repo")
+	cmd.MarkFlagRequired("mapping")
+	cmd.MarkFlagRequired("state")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/kkentzo/gl-to-gh/gitlab/gitlabtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Sync(t *testing.T) {
+	gl := gitlabtest.NewServer()
+	defer gl.Close()
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	first := gl.AddIssue("group/project", "First issue", "a description")
+	gl.AddNote(first, "A first comment")
+	second := gl.AddIssue("group/project", "Second issue", "")
+	gl.Update(second, func(issue *gitlabtest.Issue) {
+		issue.State, issue.ClosedAt = "closed", time.Now()
+	})
+
+	args := []string{"sync",
+		"--source", "api",
+		"--gitlab-url", gl.URL,
+		"--gitlab-token", "gitlab-token",
+		"--project", "group/project",
+		"--repo", testRepo,
+		"--token", "test-token",
+		"--api-url", srv.URL,
+		"--mapping", filepath.Join(dir, "mapping.json"),
+		"--state", filepath.Join(dir, "state.json"),
+		"--report", filepath.Join(dir, "report.json"),
+	}
+	assert.Nil(t, execute(args...))
+	repo := srv.Repo(testRepo)
+	if assert.Len(t, repo.Issues, 2) {
+		assert.Len(t, repo.Issues[0].Comments, 1)
+		// closed issues are imported with a label (like import does)
+		assert.Equal(t, []string{"closed"}, repo.Issues[1].Labels)
+	}
+
+	// changes since the last sync
+	gl.AddNote(first, "A second comment")
+	gl.Update(first, func(issue *gitlabtest.Issue) {
+		issue.Title = "First issue (renamed)"
+		issue.State, issue.ClosedAt = "closed", time.Now()
+	})
+	// an issue that is still closed is left alone
+	gl.Update(second, func(issue *gitlabtest.Issue) {})
+	third := gl.AddIssue("group/project", "Third issue", "")
+	gl.AddNote(third, "A comment on a new issue")
+
+	assert.Nil(t, execute(args...))
+	if assert.Len(t, repo.Issues, 3) {
+		assert.Equal(t, "First issue (renamed)", repo.Issues[0].Title)
+		assert.Equal(t, "closed", repo.Issues[0].State)
+		assert.Equal(t, []string{"closed"}, repo.Issues[0].Labels)
+		if assert.Len(t, repo.Issues[0].Comments, 2) {
+			assert.Contains(t, repo.Issues[0].Comments[1].Body, "A second comment")
+		}
+		assert.Equal(t, "open", repo.Issues[1].State)
+		assert.Equal(t, []string{"closed"}, repo.Issues[1].Labels)
+		assert.Len(t, repo.Issues[2].Comments, 1)
+	}
+	report := readReport(t, dir)
+	assert.Equal(t, []int{3}, report.Created)
+	assert.Equal(t, "updated", report.Issues[0].Outcome)
+	assert.Equal(t, "unchanged", report.Issues[1].Outcome)
+
+	// reopening is propagated and nothing is duplicated
+	gl.Update(first, func(issue *gitlabtest.Issue) {
+		issue.State, issue.ClosedAt = "opened", time.Time{}
+	})
+	assert.Nil(t, execute(args...))
+	assert.Equal(t, "open", repo.Issues[0].State)
+	assert.Empty(t, repo.Issues[0].Labels)
+	assert.Len(t, repo.Issues, 3)
+	assert.Len(t, repo.Issues[0].Comments, 2)
+	assert.Len(t, repo.Issues[2].Comments, 1)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/spf13/cobra"
+)
+
+func UpdateCommand(globals *GlobalVariables) *cobra.Command {
+	var (
+		startFromId int
+		endAtId     int
+		repo        string
+		auth        AuthVariables
+		delay       time.Duration
+		dryRun      bool
+		showDiff    bool
+		mappingPath string
+		reportPath  string
+
+		descr = "Re-render already imported issues and comments and update the ones that have changed"
+		cmd   = &cobra.Command{
+			Use:   "update",
+			Short: descr,
+			Long:  descr,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				target, err := auth.NewTarget(dryRun, globals.Debug, delay)
+				if err != nil {
+					return err
+				}
+				defer auth.Close()
+
+				opts, err := globals.RenderOptions()
+				if err != nil {
+					return configError(err)
+				}
+
+				issues, err := globals.Issues(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				mapping := migrate.NewMapping()
+				if mappingPath != "" {
+					if mapping, err = migrate.LoadMapping(mappingPath); err != nil {
+						return configError(err)
+					}
+					if dryRun {
+						mapping.Discard()
+					}
+				}
+
+				migrator, err := migrate.New(target, issues, migrate.Options{
+					Repo:    repo,
+					Start:   startFromId,
+					End:     endAtId,
+					Render:  opts,
+					Mapping: mapping,
+					Events: migrate.Events{
+						IssueChanged: func(iid int, number int, diff string) {
+							log.Printf("[#%d] issue #%d has changed", iid, number)
+							if showDiff {
+								fmt.Fprintln(cmd.OutOrStdout(), diff)
+							}
+						},
+						CommentChanged: func(iid int, id int64, diff string) {
+							log.Printf("[#%d] comment %d has changed", iid, id)
+							if showDiff {
+								fmt.Fprintln(cmd.OutOrStdout(), diff)
+							}
+						},
+					},
+				})
+				if err != nil {
+					return configError(err)
+				}
+
+				ctx, stop := interruptible(cmd.Context())
+				defer stop()
+				err = migrator.Update(ctx, showDiff)
+
+				report := migrator.Report()
+				log.Printf("Summary:\n%s", report.Summarize())
+				if reportPath != "" {
+					if werr := report.Write(reportPath); werr != nil {
+						log.Printf("error: %v", werr)
+					}
+				}
+				return err
+			},
+		}
+	)
+
+	cmd.Flags().IntVar(&startFromId, "start", 1, "ID to start the update from (lower IDs will be skipped)")
+	cmd.Flags().IntVar(&endAtId, "end", 0, "ID to stop the update at (inclusive)")
+	cmd.Flags().StringVarP(&repo, "repo", "r", "", "the target github repo in the form 'user_or_org/repo_name'")
+	authFlags(cmd, &auth)
+	cmd.Flags().DurationVar(&delay, "delay", 0, "minimum delay between successive API calls that create content (requests are paced according to github's rate limits anyway)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "simulate the changes without making them in github (the state of the repo is still read)")
+	cmd.Flags().BoolVar(&showDiff, "diff", false, "preview the differences without updating anything")
+	cmd.Flags().StringVar(&mappingPath, "mapping", "", "file that records the github issue numbers of the imported gitlab issues")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write a JSON report of the run to the specified file")
+	cmd.MarkFlagRequired("repo")
+	return requireGlobalFlags(cmd, globals, []string{})
+}
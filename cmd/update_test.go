@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Update(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+
+	assert.Nil(t, execute(importArgs(srv, dir)...))
+	assert.Nil(t, execute(importArgs(srv, dir, "--comments")...))
+
+	// the comments are rendered differently
+	args := []string{"update",
+		"--export", "testdata/issues.ndjson",
+		"--repo", testRepo,
+		"--token", "test-token",
+		"--api-url", srv.URL,
+		"--mapping", filepath.Join(dir, "mapping.json"),
+		"--report", filepath.Join(dir, "report.json"),
+		"--users", "kkentzo=482361",
+		"--replace", "comment=note",
+	}
+
+	// a preview does not count anything as updated
+	assert.Nil(t, execute(append(args, "--diff")...))
+	repo := srv.Repo(testRepo)
+	assert.Contains(t, repo.Issues[0].Comments[0].Body, "A first comment")
+	report := readReport(t, dir)
+	assert.Equal(t, "changed", report.Issues[0].Outcome)
+	assert.Equal(t, 0, report.Issues[0].UpdatedComments)
+	assert.Equal(t, 2, report.Issues[0].ChangedComments)
+	summary := report.Summarize()
+	assert.Contains(t, summary, "0 updated, 2 unchanged")
+	assert.Contains(t, summary, "Comments: 0 posted, 0 updated")
+	assert.Contains(t, summary, "Previewed updates: 1 issues, 2 comments")
+
+	assert.Nil(t, execute(args...))
+	if assert.Len(t, repo.Issues[0].Comments, 2) {
+		assert.Contains(t, repo.Issues[0].Comments[0].Body, "A first note")
+	}
+	report = readReport(t, dir)
+	assert.Equal(t, "updated", report.Issues[0].Outcome)
+	// the edited comments are not counted as posted
+	assert.Equal(t, 0, report.Issues[0].Comments)
+	assert.Equal(t, 2, report.Issues[0].UpdatedComments)
+}
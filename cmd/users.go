@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/kkentzo/gl-to-gh/gitlab"
 	"github.com/spf13/cobra"
 )
 
@@ -14,12 +13,10 @@ func UsersCommand(globals *GlobalVariables) *cobra.Command {
 			Use:   "users",
 			Short: descr,
 			Long:  descr,
-			Run: func(cmd *cobra.Command, args []string) {
-				// parse ndjson
-				issues, err := gitlab.Parse(globals.ExportPath, globals.CommentExclusionFilter)
+			RunE: func(cmd *cobra.Command, args []string) error {
+				issues, err := globals.Issues(cmd.Context())
 				if err != nil {
-					fmt.Fprintf(cmd.OutOrStderr(), "error: %v\n", err)
-					return
+					return err
 				}
 
 				// find unique users in issues only
@@ -33,9 +30,10 @@ func UsersCommand(globals *GlobalVariables) *cobra.Command {
 				for uid, _ := range uids {
 					fmt.Fprintf(cmd.OutOrStderr(), "%d\n", uid)
 				}
+				return nil
 			},
 		}
 	)
 
-	return requireGlobalFlags(cmd, globals, []string{"export"})
+	return requireGlobalFlags(cmd, globals, []string{})
 }
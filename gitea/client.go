@@ -0,0 +1,384 @@
+// Package gitea implements github.Target with the API of Gitea and Forgejo
+// so that the converted issues can be migrated to a self-hosted instance
+//
+// The issues are created through the regular issue API, like on github. Gitea's migration
+// endpoint (POST /repos/migrate) is not used: it imports a whole repository by pulling it
+// from gitlab itself, which bypasses the conversion of the issues (and the mapping that
+// the other commands rely on) and requires the instance to reach the gitlab server.
+// The one migration-friendly option of the issue API that is used is creating
+// closed issues as closed. They still get github.ClosedLabel like on github, which
+// the sync keeps in step with their state.
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// page size for list requests (gitea's default maximum)
+	perPage = 50
+	// the color of labels that are created implicitly
+	defaultColor = "#ededed"
+	// maximum number of retries of a rate limited request
+	maxRetries = 3
+)
+
+type Client struct {
+	endpoint  string
+	token     string
+	client    *http.Client
+	scheduler *github.Scheduler
+	debug     bool
+	count     atomic.Int64
+
+	// label IDs indexed by repo and label name
+	mu     sync.Mutex
+	labels map[string]map[string]int64
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTransport sets the transport of the client's http requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.client.Transport = transport
+	}
+}
+
+// WithScheduler sets the scheduler that paces the requests of the client
+func WithScheduler(scheduler *github.Scheduler) Option {
+	return func(c *Client) {
+		c.scheduler = scheduler
+	}
+}
+
+// NewClient returns a client for the gitea instance at url (e.g. https://gitea.example.com)
+func NewClient(url, token string, debug bool, opts ...Option) *Client {
+	c := &Client{
+		endpoint:  strings.TrimSuffix(url, "/") + "/api/v1",
+		token:     token,
+		client:    &http.Client{Timeout: defaultTimeout},
+		scheduler: github.NewFloorScheduler(0),
+		debug:     debug,
+		labels:    map[string]map[string]int64{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) RequestCount() int {
+	return int(c.count.Load())
+}
+
+// do sends the request and decodes the response into result (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, payload interface{}, expected int, result interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("failed to serialize request: %v", err)
+		}
+	}
+	resp, respBody, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != expected {
+		return &github.StatusError{StatusCode: resp.StatusCode, Expected: expected, Header: resp.Header, Body: respBody}
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("error parsing response body: %v", err)
+		}
+	}
+	return nil
+}
+
+// send sends the request (retrying it if it is rate limited) and returns the response and its body
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.scheduler.Wait(github.WaitContext(ctx), method); err != nil {
+			return nil, nil, fmt.Errorf("http client: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create the http request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "gl2gh")
+		req.Header.Set("Authorization", "token "+c.token)
+		if c.debug {
+			log.Printf("[http] %s %s\n%s", method, req.URL, string(body))
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("http client: request error: %w", err)
+		}
+		c.count.Add(1)
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("http client: failed to read response body: %v", err)
+		}
+		if c.debug {
+			log.Printf("[http] STATUS %d", resp.StatusCode)
+		}
+
+		if backoff, limited := c.scheduler.Observe(resp, respBody); limited && attempt < maxRetries {
+			log.Printf("[http] rate limit exceeded: retrying in %v", backoff)
+			continue
+		}
+		return resp, respBody, nil
+	}
+}
+
+type issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	State  string `json:"state"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+func (i *issue) convert() *github.Issue {
+	ghIssue := &github.Issue{Number: i.Number, Title: i.Title, Body: i.Body, State: i.State, Labels: []string{}}
+	for _, label := range i.Labels {
+		ghIssue.Labels = append(ghIssue.Labels, label.Name)
+	}
+	return ghIssue
+}
+
+func (c *Client) CreateIssue(ctx context.Context, repo string, ghIssue *github.Issue) error {
+	labels, err := c.labelIds(ctx, repo, ghIssue.Labels)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"title":     ghIssue.Title,
+		"body":      ghIssue.Body,
+		"assignees": ghIssue.Assignees,
+		"labels":    labels,
+		// gitea can create issues in their final state
+		"closed": ghIssue.State == "closed",
+	}
+	response := &issue{}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues", repo), payload, http.StatusCreated, response); err != nil {
+		return fmt.Errorf("error creating issue: %w", err)
+	}
+	ghIssue.Number = response.Number
+	return nil
+}
+
+func (c *Client) UpdateIssue(ctx context.Context, repo string, number int, update github.IssueUpdate) error {
+	// gitea replaces the labels of an issue through a separate endpoint (by ID)
+	if update.Labels != nil {
+		labels, err := c.labelIds(ctx, repo, *update.Labels)
+		if err != nil {
+			return err
+		}
+		path := fmt.Sprintf("/repos/%s/issues/%d/labels", repo, number)
+		if err := c.do(ctx, http.MethodPut, path, map[string][]int64{"labels": labels}, http.StatusOK, nil); err != nil {
+			return fmt.Errorf("error updating the labels of issue #%d: %w", number, err)
+		}
+		update.Labels = nil
+		if update == (github.IssueUpdate{}) {
+			return nil
+		}
+	}
+	// gitea responds to issue edits with 201
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/issues/%d", repo, number), update, http.StatusCreated, nil); err != nil {
+		return fmt.Errorf("error updating issue #%d: %w", number, err)
+	}
+	return nil
+}
+
+func (c *Client) GetIssue(ctx context.Context, repo string, number int) (*github.Issue, error) {
+	response := &issue{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d", repo, number), nil, http.StatusOK, response); err != nil {
+		return nil, fmt.Errorf("error fetching issue #%d: %w", number, err)
+	}
+	return response.convert(), nil
+}
+
+func (c *Client) ListIssues(ctx context.Context, repo string) ([]*github.Issue, error) {
+	issues := []*github.Issue{}
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/issues?state=all&type=issues&limit=%d&page=%d", repo, perPage, page)
+		batch := []*issue{}
+		if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &batch); err != nil {
+			return nil, fmt.Errorf("error listing issues: %w", err)
+		}
+		for _, i := range batch {
+			if i.PullRequest == nil || string(i.PullRequest) == "null" {
+				issues = append(issues, i.convert())
+			}
+		}
+		if len(batch) < perPage {
+			break
+		}
+	}
+	// gitea lists the newest issues first
+	for i, j := 0, len(issues)-1; i < j; i, j = i+1, j-1 {
+		issues[i], issues[j] = issues[j], issues[i]
+	}
+	return issues, nil
+}
+
+type comment struct {
+	Id   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+func (c *Client) CreateComment(ctx context.Context, repo string, number int, ghComment *github.Comment) error {
+	response := &comment{}
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number)
+	if err := c.do(ctx, http.MethodPost, path, map[string]string{"body": ghComment.Body}, http.StatusCreated, response); err != nil {
+		return fmt.Errorf("error posting comment: %w", err)
+	}
+	ghComment.Id = response.Id
+	return nil
+}
+
+func (c *Client) EditComment(ctx context.Context, repo string, id int64, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/comments/%d", repo, id)
+	if err := c.do(ctx, http.MethodPatch, path, map[string]string{"body": body}, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("error editing comment: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) ListComments(ctx context.Context, repo string, number int) ([]*github.Comment, error) {
+	// the comments of an issue are not paginated
+	batch := []*comment{}
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number), nil, http.StatusOK, &batch); err != nil {
+		return nil, fmt.Errorf("error listing comments: %w", err)
+	}
+	comments := []*github.Comment{}
+	for _, c := range batch {
+		comments = append(comments, &github.Comment{Id: c.Id, Body: c.Body})
+	}
+	return comments, nil
+}
+
+func (c *Client) CreateIssueReaction(ctx context.Context, repo string, number int, content string) error {
+	return c.createReaction(ctx, fmt.Sprintf("/repos/%s/issues/%d/reactions", repo, number), content)
+}
+
+func (c *Client) CreateCommentReaction(ctx context.Context, repo string, id int64, content string) error {
+	return c.createReaction(ctx, fmt.Sprintf("/repos/%s/issues/comments/%d/reactions", repo, id), content)
+}
+
+func (c *Client) createReaction(ctx context.Context, path, content string) error {
+	if err := c.do(ctx, http.MethodPost, path, map[string]string{"content": content}, http.StatusCreated, nil); err != nil {
+		// gitea responds with 200 if the reaction already exists
+		var statusErr *github.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusOK {
+			return nil
+		}
+		return fmt.Errorf("error creating reaction %s: %w", content, err)
+	}
+	return nil
+}
+
+func (c *Client) CreateLabel(ctx context.Context, repo string, label *github.Label) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.label(ctx, repo, label)
+	return err
+}
+
+// labelIds returns the IDs of the named labels (gitea refers to labels by ID)
+// creating the ones that do not exist
+func (c *Client) labelIds(ctx context.Context, repo string, names []string) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids := []int64{}
+	for _, name := range names {
+		id, err := c.label(ctx, repo, &github.Label{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// label returns the ID of label, creating it if it does not exist (must be called with the lock held)
+func (c *Client) label(ctx context.Context, repo string, label *github.Label) (int64, error) {
+	labels, ok := c.labels[repo]
+	if !ok {
+		labels = map[string]int64{}
+		for page := 1; ; page++ {
+			batch := []struct {
+				Id   int64  `json:"id"`
+				Name string `json:"name"`
+			}{}
+			path := fmt.Sprintf("/repos/%s/labels?limit=%d&page=%d", repo, perPage, page)
+			if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &batch); err != nil {
+				return 0, fmt.Errorf("error listing labels: %w", err)
+			}
+			for _, l := range batch {
+				labels[l.Name] = l.Id
+			}
+			if len(batch) < perPage {
+				break
+			}
+		}
+		c.labels[repo] = labels
+	}
+	if id, ok := labels[label.Name]; ok {
+		return id, nil
+	}
+
+	color := label.Color
+	if color == "" {
+		color = defaultColor
+	} else if !strings.HasPrefix(color, "#") {
+		color = "#" + color
+	}
+	response := struct {
+		Id int64 `json:"id"`
+	}{}
+	payload := map[string]string{"name": label.Name, "color": color, "description": label.Description}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/labels", repo), payload, http.StatusCreated, &response); err != nil {
+		return 0, fmt.Errorf("error creating label %s: %w", label.Name, err)
+	}
+	labels[label.Name] = response.Id
+	return response.Id, nil
+}
+
+// CreateMilestone creates the milestone and sets its Number to the milestone's ID
+// (which is how gitea refers to milestones)
+func (c *Client) CreateMilestone(ctx context.Context, repo string, milestone *github.Milestone) error {
+	response := struct {
+		Id int `json:"id"`
+	}{}
+	payload := map[string]string{"title": milestone.Title, "description": milestone.Description, "state": milestone.State}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/milestones", repo), payload, http.StatusCreated, &response); err != nil {
+		return fmt.Errorf("error creating milestone %s: %w", milestone.Title, err)
+	}
+	milestone.Number = response.Id
+	return nil
+}
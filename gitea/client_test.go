@@ -0,0 +1,86 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/gitea/giteatest"
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_Issues(t *testing.T) {
+	srv := giteatest.NewServer()
+	defer srv.Close()
+	client := NewClient(srv.URL+"/", "token", false)
+	ctx := context.Background()
+
+	assert.Nil(t, client.CreateLabel(ctx, "o/r", &github.Label{Name: "bug", Color: "ff0000"}))
+	for i := 0; i < 60; i++ {
+		issue := &github.Issue{Title: "an issue", Body: "body", Labels: []string{"bug", "gitlab"}, State: "closed"}
+		assert.Nil(t, client.CreateIssue(ctx, "o/r", issue))
+		assert.Equal(t, i+1, issue.Number)
+	}
+
+	repo := srv.Repo("o/r")
+	if assert.Len(t, repo.Labels, 2) {
+		assert.Equal(t, "#ff0000", repo.Labels[0].Color)
+		assert.Equal(t, "#ededed", repo.Labels[1].Color)
+	}
+	assert.Equal(t, []string{"bug", "gitlab"}, repo.Issues[0].Labels)
+	assert.Equal(t, "closed", repo.Issues[0].State)
+
+	// listed in ascending order across pages
+	issues, err := client.ListIssues(ctx, "o/r")
+	assert.Nil(t, err)
+	if assert.Len(t, issues, 60) {
+		assert.Equal(t, 1, issues[0].Number)
+		assert.Equal(t, 60, issues[59].Number)
+	}
+
+	assert.Nil(t, client.UpdateIssue(ctx, "o/r", 2, github.IssueUpdate{Title: "renamed", State: "open"}))
+	issue, err := client.GetIssue(ctx, "o/r", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", issue.Title)
+	assert.Equal(t, "open", issue.State)
+	assert.Equal(t, "body", issue.Body)
+
+	comment := &github.Comment{Body: "a comment"}
+	assert.Nil(t, client.CreateComment(ctx, "o/r", 2, comment))
+	assert.Nil(t, client.EditComment(ctx, "o/r", comment.Id, "edited"))
+	comments, err := client.ListComments(ctx, "o/r", 2)
+	assert.Nil(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, comment.Id, comments[0].Id)
+		assert.Equal(t, "edited", comments[0].Body)
+	}
+
+	milestone := &github.Milestone{Title: "v1"}
+	assert.Nil(t, client.CreateMilestone(ctx, "o/r", milestone))
+	assert.Equal(t, int(repo.Milestones[0].Id), milestone.Number)
+}
+
+func Test_Client_RetriesRateLimitedRequests(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts += 1
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL, "token", false, WithScheduler(github.NewFloorScheduler(time.Millisecond)))
+
+	comment := &github.Comment{Body: "a comment"}
+	assert.Nil(t, client.CreateComment(context.Background(), "o/r", 1, comment))
+	assert.Equal(t, int64(1), comment.Id)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2, client.RequestCount())
+}
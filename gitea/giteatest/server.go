@@ -0,0 +1,397 @@
+// Package giteatest provides an in-process fake of the gitea (and forgejo) API
+// that keeps state, for testing migrations to gitea offline
+package giteatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Comment struct {
+	Id        int64    `json:"id"`
+	Body      string   `json:"body"`
+	Reactions []string `json:"-"`
+}
+
+type Issue struct {
+	Id        int64
+	Number    int
+	Title     string
+	Body      string
+	State     string
+	Labels    []string
+	Assignees []string
+	Comments  []*Comment
+	Reactions []string
+}
+
+type Label struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type Milestone struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+	State string `json:"state"`
+}
+
+// Repo is the state of a fake gitea repository
+type Repo struct {
+	Issues     []*Issue
+	Labels     []*Label
+	Milestones []*Milestone
+}
+
+// Server is a fake gitea API (served under /api/v1)
+// Repos are created on demand the first time they are accessed
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	repos  map[string]*Repo
+	nextId int64
+}
+
+func NewServer() *Server {
+	s := &Server{repos: map[string]*Repo{}, nextId: 100}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Repo returns the state of the repo with the specified full name (e.g. "owner/repo")
+func (s *Server) Repo(name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(name)
+}
+
+// Label returns the label of the repo with the specified ID
+func (r *Repo) Label(id int64) *Label {
+	for _, label := range r.Labels {
+		if label.Id == id {
+			return label
+		}
+	}
+	return nil
+}
+
+func (s *Server) repo(name string) *Repo {
+	r, ok := s.repos[name]
+	if !ok {
+		r = &Repo{}
+		s.repos[name] = r
+	}
+	return r
+}
+
+func (s *Server) id() int64 {
+	s.nextId++
+	return s.nextId
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "token ") {
+		respond(w, http.StatusUnauthorized, map[string]string{"message": "token is required"})
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1"), "/"), "/")
+	if len(parts) < 3 || parts[0] != "repos" {
+		respond(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	name := parts[1] + "/" + parts[2]
+	s.route(w, r, name, s.repo(name), parts[3:])
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, name string, repo *Repo, parts []string) {
+	notFound := func() { respond(w, http.StatusNotFound, map[string]string{"message": "Not Found"}) }
+
+	switch {
+	case len(parts) == 1 && parts[0] == "issues" && r.Method == http.MethodPost:
+		payload := struct {
+			Title     string   `json:"title"`
+			Body      string   `json:"body"`
+			Assignees []string `json:"assignees"`
+			Labels    []int64  `json:"labels"`
+			Closed    bool     `json:"closed"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		if payload.Title == "" {
+			respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "title is required"})
+			return
+		}
+		issue := &Issue{Id: s.id(), Number: len(repo.Issues) + 1, Title: payload.Title, Body: payload.Body,
+			State: "open", Assignees: payload.Assignees}
+		if payload.Closed {
+			issue.State = "closed"
+		}
+		for _, id := range payload.Labels {
+			label := repo.Label(id)
+			if label == nil {
+				respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "label does not exist"})
+				return
+			}
+			issue.Labels = append(issue.Labels, label.Name)
+		}
+		repo.Issues = append(repo.Issues, issue)
+		respond(w, http.StatusCreated, issueJSON(issue))
+
+	case len(parts) == 1 && parts[0] == "issues" && r.Method == http.MethodGet:
+		// newest first
+		items := []interface{}{}
+		for idx := len(repo.Issues) - 1; idx >= 0; idx-- {
+			items = append(items, issueJSON(repo.Issues[idx]))
+		}
+		respond(w, http.StatusOK, paginate(r, items))
+
+	case len(parts) == 2 && parts[0] == "issues":
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			respond(w, http.StatusOK, issueJSON(issue))
+		case http.MethodPatch:
+			payload := struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+				State string `json:"state"`
+			}{}
+			if !decode(w, r, &payload) {
+				return
+			}
+			if payload.Title != "" {
+				issue.Title = payload.Title
+			}
+			if payload.Body != "" {
+				issue.Body = payload.Body
+			}
+			if payload.State != "" {
+				issue.State = payload.State
+			}
+			respond(w, http.StatusCreated, issueJSON(issue))
+		default:
+			notFound()
+		}
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "labels" && r.Method == http.MethodPut:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		payload := struct {
+			Labels []int64 `json:"labels"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		issue.Labels = []string{}
+		for _, id := range payload.Labels {
+			label := repo.Label(id)
+			if label == nil {
+				respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "label does not exist"})
+				return
+			}
+			issue.Labels = append(issue.Labels, label.Name)
+		}
+		respond(w, http.StatusOK, issueJSON(issue)["labels"])
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "comments":
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			respond(w, http.StatusOK, issue.Comments)
+		case http.MethodPost:
+			comment := &Comment{}
+			if !decode(w, r, comment) {
+				return
+			}
+			comment.Id = s.id()
+			issue.Comments = append(issue.Comments, comment)
+			respond(w, http.StatusCreated, comment)
+		default:
+			notFound()
+		}
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "reactions" && r.Method == http.MethodPost:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		react(w, r, &issue.Reactions)
+
+	case len(parts) == 4 && parts[0] == "issues" && parts[1] == "comments" && parts[3] == "reactions" && r.Method == http.MethodPost:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for _, issue := range repo.Issues {
+			for _, comment := range issue.Comments {
+				if comment.Id == id {
+					react(w, r, &comment.Reactions)
+					return
+				}
+			}
+		}
+		notFound()
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[1] == "comments" && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for _, issue := range repo.Issues {
+			for _, comment := range issue.Comments {
+				if comment.Id == id {
+					if !decode(w, r, comment) {
+						return
+					}
+					comment.Id = id
+					respond(w, http.StatusOK, comment)
+					return
+				}
+			}
+		}
+		notFound()
+
+	case len(parts) == 1 && parts[0] == "labels":
+		switch r.Method {
+		case http.MethodGet:
+			items := []interface{}{}
+			for _, label := range repo.Labels {
+				items = append(items, label)
+			}
+			respond(w, http.StatusOK, paginate(r, items))
+		case http.MethodPost:
+			label := &Label{}
+			if !decode(w, r, label) {
+				return
+			}
+			for _, l := range repo.Labels {
+				if l.Name == label.Name {
+					respond(w, http.StatusConflict, map[string]string{"message": "label already exists"})
+					return
+				}
+			}
+			if !strings.HasPrefix(label.Color, "#") {
+				respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "invalid color"})
+				return
+			}
+			label.Id = s.id()
+			repo.Labels = append(repo.Labels, label)
+			respond(w, http.StatusCreated, label)
+		default:
+			notFound()
+		}
+
+	case len(parts) == 1 && parts[0] == "milestones" && r.Method == http.MethodPost:
+		milestone := &Milestone{}
+		if !decode(w, r, milestone) {
+			return
+		}
+		milestone.Id = s.id()
+		if milestone.State == "" {
+			milestone.State = "open"
+		}
+		repo.Milestones = append(repo.Milestones, milestone)
+		respond(w, http.StatusCreated, milestone)
+
+	default:
+		notFound()
+	}
+}
+
+func issueJSON(issue *Issue) map[string]interface{} {
+	labels := []map[string]string{}
+	for _, label := range issue.Labels {
+		labels = append(labels, map[string]string{"name": label})
+	}
+	return map[string]interface{}{
+		"id":           issue.Id,
+		"number":       issue.Number,
+		"title":        issue.Title,
+		"body":         issue.Body,
+		"state":        issue.State,
+		"labels":       labels,
+		"pull_request": nil,
+	}
+}
+
+func findIssue(repo *Repo, number string) *Issue {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil
+	}
+	for _, issue := range repo.Issues {
+		if issue.Number == n {
+			return issue
+		}
+	}
+	return nil
+}
+
+// paginate returns the page of items requested with the page and limit query parameters
+func paginate(r *http.Request, items []interface{}) []interface{} {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 30
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * limit
+	if start >= len(items) {
+		return []interface{}{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// react adds the reaction of the request to reactions unless it already exists
+func react(w http.ResponseWriter, r *http.Request, reactions *[]string) {
+	payload := struct {
+		Content string `json:"content"`
+	}{}
+	if !decode(w, r, &payload) {
+		return
+	}
+	for _, content := range *reactions {
+		if content == payload.Content {
+			respond(w, http.StatusOK, payload)
+			return
+		}
+	}
+	*reactions = append(*reactions, payload.Content)
+	respond(w, http.StatusCreated, payload)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,160 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// lifetime of the JWT that authenticates the app (github allows up to 10 minutes)
+	jwtLifetime = 9 * time.Minute
+	// installation tokens are refreshed when they are about to expire within this window
+	refreshWindow = 5 * time.Minute
+)
+
+// TokenSource provides the token that authenticates the requests of a Client
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a personal access token
+type StaticToken string
+
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// AppTokenSource authenticates as a github app installation
+// Installation tokens are obtained using a JWT signed with the app's private key
+// and are refreshed automatically before they expire
+type AppTokenSource struct {
+	endpoint       string
+	appId          string
+	installationId int64
+	key            *rsa.PrivateKey
+	client         *http.Client
+	now            func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewAppTokenSource returns a token source for the app installation
+// keyPEM is the app's private key in PEM format (PKCS#1 or PKCS#8)
+// endpoint is the github API endpoint (the public API if empty)
+func NewAppTokenSource(endpoint, appId string, installationId int64, keyPEM []byte) (*AppTokenSource, error) {
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == "" {
+		endpoint = apiEndpoint
+	}
+	return &AppTokenSource{
+		endpoint:       endpoint,
+		appId:          appId,
+		installationId: installationId,
+		key:            key,
+		client:         &http.Client{Timeout: defaultTimeout},
+		now:            time.Now,
+	}, nil
+}
+
+func (s *AppTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.expiresAt.Sub(s.now()) > refreshWindow {
+		return s.token, nil
+	}
+
+	jwt, err := s.jwt()
+	if err != nil {
+		return "", err
+	}
+	uri := urljoin(s.endpoint, fmt.Sprintf("/app/installations/%d/access_tokens", s.installationId))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the token request: %v", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "gl2gh")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request installation token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read installation token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("failed to request installation token: %w",
+			&StatusError{StatusCode: resp.StatusCode, Expected: http.StatusCreated, Header: resp.Header, Body: body})
+	}
+
+	response := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse installation token response: %v", err)
+	}
+	s.token, s.expiresAt = response.Token, response.ExpiresAt
+	return s.token, nil
+}
+
+// jwt returns a JSON Web Token (RS256) that authenticates the app
+func (s *AppTokenSource) jwt() (string, error) {
+	now := s.now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		// allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": s.appId,
+	})
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %v", err)
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
+
+func parsePrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode the private key: no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
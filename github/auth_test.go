@@ -0,0 +1,102 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fake token endpoint that verifies the app's JWT and hands out installation tokens
+func newTokenServer(t *testing.T, key *rsa.PrivateKey, expiresIn time.Duration) (*httptest.Server, *int) {
+	issued := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/app/installations/42/access_tokens", r.URL.Path)
+
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if !assert.Len(t, parts, 3) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims := map[string]interface{}{}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.Nil(t, json.Unmarshal(payload, &claims))
+		assert.Equal(t, "1234", claims["iss"])
+
+		issued += 1
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      fmt.Sprintf("ghs_token%d", issued),
+			"expires_at": time.Now().Add(expiresIn).Format(time.RFC3339),
+		})
+	}))
+	return srv, &issued
+}
+
+func newTestKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, keyPEM
+}
+
+func Test_AppTokenSource(t *testing.T) {
+	key, keyPEM := newTestKey(t)
+	srv, issued := newTokenServer(t, key, time.Hour)
+	defer srv.Close()
+
+	tokens, err := NewAppTokenSource(srv.URL, "1234", 42, keyPEM)
+	assert.Nil(t, err)
+
+	token, err := tokens.Token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_token1", token)
+
+	// the token is cached while it is valid
+	token, err = tokens.Token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_token1", token)
+	assert.Equal(t, 1, *issued)
+
+	// and refreshed before it expires
+	tokens.now = func() time.Time { return time.Now().Add(56 * time.Minute) }
+	token, err = tokens.Token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_token2", token)
+}
+
+func Test_AppTokenSource_InvalidKey(t *testing.T) {
+	_, err := NewAppTokenSource("", "1234", 42, []byte("not a key"))
+	assert.NotNil(t, err)
+
+	// a token signed with the wrong key is rejected
+	key, _ := newTestKey(t)
+	_, otherPEM := newTestKey(t)
+	srv, _ := newTokenServer(t, key, time.Hour)
+	defer srv.Close()
+
+	tokens, err := NewAppTokenSource(srv.URL, "1234", 42, otherPEM)
+	assert.Nil(t, err)
+	_, err = tokens.Token(context.Background())
+	assert.NotNil(t, err)
+}
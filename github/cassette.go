@@ -0,0 +1,198 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Interaction is a request and its response as stored in a cassette (one JSON object per line)
+type Interaction struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that appends every request and its response
+// to a cassette file. The Authorization header is never recorded and anything that
+// looks like a token is redacted from the bodies.
+type Recorder struct {
+	next    http.RoundTripper
+	secrets []string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder returns a recorder that sends the requests through next (the default
+// transport if nil) and records them to the cassette at path, which is truncated.
+// secrets are redacted from the recorded bodies (in addition to github's token formats).
+func NewRecorder(path string, next http.RoundTripper, secrets ...string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %v", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next, secrets: secrets, file: file}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read request body: %v", err)
+		}
+		reqBody, _ = io.ReadAll(body)
+		body.Close()
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	err = r.write(&Interaction{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: r.redact(string(reqBody)),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        r.redact(string(respBody)),
+	})
+	return resp, err
+}
+
+// write appends the interaction to the cassette immediately
+// so that the cassette is complete even if the process dies
+func (r *Recorder) write(interaction *Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("cassette: failed to serialize interaction: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("cassette: failed to write interaction: %v", err)
+	}
+	return nil
+}
+
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+	return Redact(s)
+}
+
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// Replayer is an http.RoundTripper that serves the responses of a cassette
+// Each request is answered by the first unused interaction with the same method and URL
+// (and request body, if the recorded one is not empty), so repeated requests
+// (e.g. retries) are answered in the order they were recorded.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// LoadCassette reads the cassette at path for replaying
+func LoadCassette(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %v", err)
+	}
+	defer file.Close()
+
+	r := &Replayer{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		interaction := &Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), interaction); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s (line %d): %v", path, line, err)
+		}
+		r.interactions = append(r.interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %v", err)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for idx, interaction := range r.interactions {
+		if r.used[idx] || interaction.Method != req.Method || interaction.URL != req.URL.String() {
+			continue
+		}
+		if interaction.RequestBody != "" && interaction.RequestBody != Redact(string(reqBody)) {
+			continue
+		}
+		r.used[idx] = true
+		header := interaction.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(interaction.Body)),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette: no recorded response for %s %s", req.Method, req.URL)
+}
+
+// Remaining returns the number of recorded interactions that have not been replayed
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	remaining := 0
+	for _, used := range r.used {
+		if !used {
+			remaining += 1
+		}
+	}
+	return remaining
+}
@@ -2,18 +2,24 @@ package github
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	apiEndpoint    = "https://api.github.com"
 	defaultTimeout = 10 * time.Second
+	// page size for list requests
+	perPage = 100
+	// number of times a request is retried after exceeding a rate limit
+	maxRetries = 3
 )
 
 type RateResponse struct {
@@ -30,35 +36,105 @@ type Rate struct {
 	ResetAt   time.Time
 }
 
+// StatusError is returned by Client.Do when the response status code
+// differs from the expected one
+type StatusError struct {
+	StatusCode int
+	Expected   int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http client: status code: %d (expected %d)", e.StatusCode, e.Expected)
+}
+
+// IsAuth returns true if the request was rejected due to invalid credentials or insufficient permissions
+func (e *StatusError) IsAuth() bool {
+	if e.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	return e.StatusCode == http.StatusForbidden && e.Header.Get("X-RateLimit-Remaining") != "0" &&
+		!bytes.Contains(e.Body, []byte("rate limit"))
+}
+
 type Client struct {
-	token  string
-	client *http.Client
-	dryRun bool
-	debug  bool
-	count  int
+	endpoint  string
+	tokens    TokenSource
+	client    *http.Client
+	scheduler *Scheduler
+	dryRun    bool
+	debug     bool
+	count     atomic.Int64
 }
 
-func NewClient(token string, dryRun, debug bool) *Client {
-	return &Client{
-		token:  token,
-		debug:  debug,
-		dryRun: dryRun,
-		client: &http.Client{Timeout: defaultTimeout},
+// Option configures a Client
+type Option func(*Client)
+
+// WithEndpoint sets the URL of the github API (e.g. for github enterprise server)
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		if endpoint != "" {
+			c.endpoint = endpoint
+		}
 	}
 }
 
+// WithTokenSource sets the source of the token that authenticates the requests
+// (instead of the static token passed to NewClient)
+func WithTokenSource(tokens TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = tokens
+	}
+}
+
+// WithScheduler sets the scheduler that paces the requests of the client
+func WithScheduler(scheduler *Scheduler) Option {
+	return func(c *Client) {
+		c.scheduler = scheduler
+	}
+}
+
+// WithTransport sets the transport of the client's http requests
+// (e.g. a Recorder or a Replayer)
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.client.Transport = transport
+	}
+}
+
+func NewClient(token string, dryRun, debug bool, opts ...Option) *Client {
+	c := &Client{
+		endpoint:  apiEndpoint,
+		tokens:    StaticToken(token),
+		debug:     debug,
+		dryRun:    dryRun,
+		client:    &http.Client{Timeout: defaultTimeout},
+		scheduler: NewScheduler(0),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// URL returns the absolute URL of the API path
+func (c *Client) URL(path string) string {
+	return urljoin(c.endpoint, path)
+}
+
 func (c *Client) RequestCount() int {
-	return c.count
+	return int(c.count.Load())
 }
 
-func (c *Client) RateLimit() (*Rate, error) {
-	req, err := c.NewRequest(http.MethodGet, urljoin(apiEndpoint, "/rate_limit"), nil)
+func (c *Client) RateLimit(ctx context.Context) (*Rate, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, c.URL("/rate_limit"), nil)
 	if err != nil {
-		return nil, fmt.Errorf("error preparing the request: %v", err)
+		return nil, fmt.Errorf("error preparing the request: %w", err)
 	}
 	resBody, err := c.Do(req, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %v", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	rateResponse := &RateResponse{}
 	if err := json.Unmarshal(resBody, rateResponse); err != nil {
@@ -69,50 +145,130 @@ func (c *Client) RateLimit() (*Rate, error) {
 	return rate, nil
 }
 
-func (c *Client) NewRequest(method, uri string, body []byte) (*http.Request, error) {
-	req, err := http.NewRequest(method, uri, bytes.NewReader(body))
+func (c *Client) NewRequest(ctx context.Context, method, uri string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create the http request: %v", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("User-Agent", "gl2gh")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	if c.debug || c.dryRun {
-		log.Printf("[http] %s %s\n%s", method, uri, string(body))
+		log.Printf("[http] %s %s\n%s", method, uri, c.redact(string(body)))
 	}
 
 	return req, nil
 }
 
+// Do sends the request (paced by the client's scheduler) and returns the response body
+// Requests that exceed a rate limit are retried after the advertised backoff
 func (c *Client) Do(req *http.Request, expectedStatusCode int) ([]byte, error) {
-	resp, err := c.client.Do(req)
+	resp, respBody, err := c.send(req)
 	if err != nil {
-		return nil, fmt.Errorf("http client: request error: %v", err)
+		return nil, err
 	}
+	if resp.StatusCode != expectedStatusCode {
+		return respBody, &StatusError{
+			StatusCode: resp.StatusCode,
+			Expected:   expectedStatusCode,
+			Header:     resp.Header,
+			Body:       respBody,
+		}
+	}
+	return respBody, nil
+}
 
-	c.count += 1
-
-	// resource was created -- read and return the body
-	defer resp.Body.Close()
-	respBody, err := io.ReadAll(resp.Body)
+// Probe sends a GET request for path and returns the response and its body
+// Unlike Do, any status code is acceptable
+func (c *Client) Probe(ctx context.Context, path string) (*http.Response, []byte, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, c.URL(path), nil)
 	if err != nil {
-		return nil, fmt.Errorf("http client: failed to read response body: %v", err)
+		return nil, nil, fmt.Errorf("error preparing the request: %w", err)
 	}
+	return c.send(req)
+}
 
-	if c.debug {
-		log.Printf("[http] STATUS %d", resp.StatusCode)
-	}
+func (c *Client) send(req *http.Request) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, nil, fmt.Errorf("http client: failed to rewind request body: %v", err)
+			}
+			req.Body = body
+		}
+		if err := c.scheduler.Wait(WaitContext(req.Context()), req.Method); err != nil {
+			return nil, nil, fmt.Errorf("http client: %w", err)
+		}
+		// the token is obtained on every attempt, since waiting may outlast an installation token
+		token, err := c.tokens.Token(req.Context())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to obtain the API token: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("http client: request error: %w", err)
+		}
+
+		c.count.Add(1)
+
+		// read and return the body
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("http client: failed to read response body: %v", err)
+		}
 
-	if resp.StatusCode != expectedStatusCode {
 		if c.debug {
-			log.Printf("[http] RESPONSE BODY\n%s", respBody)
+			log.Printf("[http] STATUS %d", resp.StatusCode)
+			if resp.StatusCode >= http.StatusBadRequest {
+				log.Printf("[http] RESPONSE BODY\n%s", c.redact(string(respBody)))
+			}
 		}
-		return respBody, fmt.Errorf("http client: status code: %d (expected %d)", resp.StatusCode, expectedStatusCode)
+
+		if backoff, limited := c.scheduler.Observe(resp, respBody); limited && attempt < maxRetries {
+			log.Printf("[http] rate limit exceeded: retrying in %v", backoff)
+			continue
+		}
+		return resp, respBody, nil
 	}
+}
 
-	return respBody, nil
+// detached carries the values of its parent context but is never cancelled
+// so that an API call in flight is allowed to complete
+type detached struct {
+	context.Context
+}
+
+// Detach returns a context for requests that must not be interrupted once they are sent
+// Waiting to send them (e.g. for a rate limit to reset) is still interrupted by ctx.
+func Detach(ctx context.Context) context.Context {
+	return detached{ctx}
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+// WaitContext returns the context that interrupts waiting to send a request with ctx
+// (the parent of a detached context)
+func WaitContext(ctx context.Context) context.Context {
+	if d, ok := ctx.(detached); ok {
+		return d.Context
+	}
+	return ctx
+}
+
+// redact masks the client's token (and anything that looks like a token) in s
+// so that it never shows up in the logs
+func (c *Client) redact(s string) string {
+	if token, ok := c.tokens.(StaticToken); ok && len(token) > 0 {
+		s = strings.ReplaceAll(s, string(token), "[REDACTED]")
+	}
+	return Redact(s)
 }
 
 func urljoin(endpoint, path string) string {
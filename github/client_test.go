@@ -0,0 +1,93 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_RetriesRateLimitedRequests(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Path: "/repos/o/r/issues", Status: http.StatusTooManyRequests, Times: 1,
+		Header: http.Header{"Retry-After": []string{"1"}}})
+
+	client := NewClient("token", false, false, WithEndpoint(srv.URL))
+	issue := &Issue{Title: "an issue", Body: "body"}
+	assert.Nil(t, issue.Post(context.Background(), client, "o/r"))
+	assert.Equal(t, 1, issue.Number)
+	assert.Equal(t, 2, client.RequestCount())
+}
+
+// countingTokens returns a new token on every call
+type countingTokens struct {
+	calls int
+	err   error
+}
+
+func (c *countingTokens) Token(ctx context.Context) (string, error) {
+	c.calls += 1
+	return fmt.Sprintf("token-%d", c.calls), c.err
+}
+
+func Test_Client_ObtainsTokenOnEveryAttempt(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Path: "/repos/o/r/issues", Status: http.StatusTooManyRequests, Times: 1,
+		Header: http.Header{"Retry-After": []string{"0"}}})
+
+	tokens := &countingTokens{}
+	client := NewClient("", false, false, WithEndpoint(srv.URL), WithTokenSource(tokens))
+	issue := &Issue{Title: "an issue", Body: "body"}
+	assert.Nil(t, issue.Post(context.Background(), client, "o/r"))
+	assert.Equal(t, 2, tokens.calls)
+
+	// the failures of the token source are not masked
+	tokens.err = &StatusError{StatusCode: http.StatusUnauthorized, Expected: http.StatusCreated}
+	err := issue.Post(context.Background(), client, "o/r")
+	var statusErr *StatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.True(t, statusErr.IsAuth())
+	}
+}
+
+func Test_Client_DetachedRequestsStopWaiting(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.Fail(githubtest.Fault{Path: "/repos/o/r/issues", Status: http.StatusTooManyRequests, Times: 1,
+		Header: http.Header{"Retry-After": []string{"3600"}}})
+
+	client := NewClient("token", false, false, WithEndpoint(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	issue := &Issue{Title: "an issue", Body: "body"}
+	start := time.Now()
+	err := issue.Post(Detach(ctx), client, "o/r")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func Test_ListIssues_SkipsPullRequests(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	client := NewClient("token", false, false, WithEndpoint(srv.URL))
+
+	for i := 0; i < 150; i++ {
+		if i == 10 {
+			srv.AddPullRequest("o/r", "a pull request")
+		} else {
+			srv.AddIssue("o/r", "an issue", "")
+		}
+	}
+
+	issues, err := client.ListIssues(context.Background(), "o/r")
+	assert.Nil(t, err)
+	assert.Len(t, issues, 149)
+	assert.Equal(t, 12, issues[10].Number)
+}
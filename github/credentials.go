@@ -0,0 +1,202 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// environment variables that are checked for a token (in order)
+var TokenEnvVars = []string{"GL2GH_TOKEN", "GITHUB_TOKEN"}
+
+// the keyring service under which gl2gh looks up a token
+const keyringService = "gl2gh"
+
+// command that queries the OS keyring through the Secret Service API
+var secretTool = "secret-tool"
+
+var errNoToken = errors.New("no token found")
+
+// ResolveToken returns the first personal access token found in (order):
+// the explicit token, the token file (if specified), the environment,
+// the gh CLI configuration for host and the OS keyring.
+// It also returns a description of where the token was found.
+func ResolveToken(explicit, tokenFile, host string) (string, string, error) {
+	if explicit != "" {
+		return explicit, "command line", nil
+	}
+	// an explicit token file is never overridden by the environment
+	if tokenFile != "" {
+		token, err := ReadTokenFile(tokenFile)
+		if err != nil {
+			return "", "", err
+		}
+		return token, "token file " + tokenFile, nil
+	}
+	for _, name := range TokenEnvVars {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, "environment variable " + name, nil
+		}
+	}
+	if token, path, err := GHConfigToken(host); err == nil {
+		return token, "gh CLI configuration " + path, nil
+	} else if !errors.Is(err, errNoToken) {
+		return "", "", err
+	}
+	if token, err := KeyringToken(host); err == nil {
+		return token, "OS keyring", nil
+	}
+	return "", "", fmt.Errorf("no github token found (use --token, --token-file, one of %v, the gh CLI or the OS keyring)", TokenEnvVars)
+}
+
+// ReadTokenFile reads the token from the file at path
+// The file must not be accessible by the group or other users
+func ReadTokenFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return "", fmt.Errorf("token file %s is accessible by other users (permissions %#o): restrict it with chmod 600", path, perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// GHConfigToken reads the token of host from the gh CLI's hosts.yml
+// It returns the token and the path of the configuration file
+func GHConfigToken(host string) (string, string, error) {
+	path := filepath.Join(ghConfigDir(), "hosts.yml")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", path, errNoToken
+	}
+	if err != nil {
+		return "", path, fmt.Errorf("failed to read gh CLI configuration: %v", err)
+	}
+	token := parseHostsToken(data, host)
+	if token == "" {
+		return "", path, errNoToken
+	}
+	return token, path, nil
+}
+
+func ghConfigDir() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gh")
+}
+
+// parseHostsToken finds the oauth_token of the active user of host in the contents
+// of gh's hosts.yml which looks like:
+//
+//	github.com:
+//	    users:
+//	        someone:
+//	            oauth_token: gho_xxx
+//	        other:
+//	            oauth_token: gho_yyy
+//	    user: someone
+//	    oauth_token: gho_xxx
+//
+// The token of the host is preferred; otherwise the token of the user (listed under users)
+// is used. Older versions of gh do not list the users.
+func parseHostsToken(data []byte, host string) string {
+	var (
+		inHost, inUsers        bool
+		hostIndent, userIndent int
+		user, hostToken        string
+		current                string
+		userTokens             = map[string]string{}
+	)
+	value := func(trimmed, key string) string {
+		return strings.Trim(strings.TrimSpace(strings.TrimPrefix(trimmed, key)), `"'`)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		// top-level keys are host names
+		if indent == 0 {
+			inHost, inUsers, hostIndent = strings.TrimSuffix(trimmed, ":") == host, false, 0
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		if hostIndent == 0 {
+			hostIndent = indent
+		}
+		switch {
+		case indent == hostIndent:
+			inUsers, userIndent = trimmed == "users:", 0
+			if strings.HasPrefix(trimmed, "user:") {
+				user = value(trimmed, "user:")
+			} else if strings.HasPrefix(trimmed, "oauth_token:") {
+				hostToken = value(trimmed, "oauth_token:")
+			}
+		case inUsers:
+			if userIndent == 0 {
+				userIndent = indent
+			}
+			if indent == userIndent {
+				current = strings.Trim(strings.TrimSuffix(trimmed, ":"), `"'`)
+			} else if strings.HasPrefix(trimmed, "oauth_token:") {
+				userTokens[current] = value(trimmed, "oauth_token:")
+			}
+		}
+	}
+	if hostToken != "" {
+		return hostToken
+	}
+	return userTokens[user]
+}
+
+// KeyringToken looks up the token of host in the OS keyring (Secret Service API)
+// either under gl2gh's own entry or under the entry of the gh CLI
+func KeyringToken(host string) (string, error) {
+	lookups := [][]string{
+		{"service", keyringService, "username", host},
+		{"service", "gh:" + host, "username", ""},
+	}
+	for _, attrs := range lookups {
+		out, err := exec.Command(secretTool, append([]string{"lookup"}, attrs...)...).Output()
+		if err != nil {
+			continue
+		}
+		if token := strings.TrimSpace(string(out)); token != "" {
+			return token, nil
+		}
+	}
+	return "", errNoToken
+}
+
+// github token formats (classic, fine-grained, app installation and OAuth tokens)
+var tokenRe = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b`)
+
+// Redact masks anything that looks like a github token in s
+func Redact(s string) string {
+	return tokenRe.ReplaceAllString(s, "[REDACTED]")
+}
@@ -0,0 +1,619 @@
+// Package githubtest provides an in-process fake of the github REST API
+// that keeps state and can inject faults, for testing migrations offline.
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rateLimit = 5000
+
+type Comment struct {
+	Id        int64    `json:"id"`
+	Body      string   `json:"body"`
+	Reactions []string `json:"-"`
+}
+
+type Issue struct {
+	Id            int64    `json:"id"`
+	Number        int      `json:"number"`
+	Title         string   `json:"title"`
+	Body          string   `json:"body"`
+	State         string   `json:"state"`
+	Labels        []string `json:"-"`
+	Assignees     []string `json:"-"`
+	Milestone     int      `json:"-"`
+	IsPullRequest bool     `json:"-"`
+	Comments      []*Comment
+	Reactions     []string `json:"-"`
+	// the ids of the issues that block the issue
+	BlockedBy []int64 `json:"-"`
+	// the ids of the sub-issues of the issue
+	SubIssues []int64 `json:"-"`
+}
+
+type Label struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type Milestone struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+}
+
+// Repo is the state of a fake github repository
+type Repo struct {
+	Issues        []*Issue
+	Labels        map[string]*Label
+	Milestones    []*Milestone
+	Collaborators []string
+	HasIssues     bool
+	// issue numbers are shared with pull requests
+	next int
+}
+
+// Fault makes the server respond to matching requests with Status
+// Path matches the request path by prefix and Times is the number of
+// requests that fail (0 means forever)
+type Fault struct {
+	Method string
+	Path   string
+	Status int
+	Times  int
+	Header http.Header
+}
+
+// Server is a fake github API
+// Repos are created on demand the first time they are accessed
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	repos    map[string]*Repo
+	faults   []*Fault
+	requests int
+	nextId   int64
+}
+
+func NewServer() *Server {
+	s := &Server{repos: map[string]*Repo{}, nextId: 1000}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Repo returns the state of the repo with the specified full name (e.g. "owner/repo")
+func (s *Server) Repo(name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(name)
+}
+
+// Fail injects a fault
+func (s *Server) Fail(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// Requests returns the number of requests served so far
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// AddIssue creates an issue in the repo and returns its number
+func (s *Server) AddIssue(repo, title, body string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newIssue(s.repo(repo), title, body).Number
+}
+
+// AddPullRequest occupies the next issue number of the repo with a pull request
+func (s *Server) AddPullRequest(repo, title string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issue := s.newIssue(s.repo(repo), title, "")
+	issue.IsPullRequest = true
+	return issue.Number
+}
+
+func (s *Server) repo(name string) *Repo {
+	r, ok := s.repos[name]
+	if !ok {
+		r = &Repo{Labels: map[string]*Label{}, HasIssues: true, next: 1}
+		s.repos[name] = r
+	}
+	return r
+}
+
+func (s *Server) newIssue(r *Repo, title, body string) *Issue {
+	s.nextId++
+	issue := &Issue{Id: s.nextId, Number: r.next, Title: title, Body: body, State: "open"}
+	r.next++
+	r.Issues = append(r.Issues, issue)
+	return issue
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rateLimit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(rateLimit-s.requests))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	w.Header().Set("X-RateLimit-Resource", "core")
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		respond(w, http.StatusUnauthorized, map[string]string{"message": "Requires authentication"})
+		return
+	}
+
+	for _, fault := range s.faults {
+		if fault.Times >= 0 && (fault.Method == "" || fault.Method == r.Method) && strings.HasPrefix(r.URL.Path, fault.Path) {
+			if fault.Times > 0 {
+				if fault.Times--; fault.Times == 0 {
+					fault.Times = -1
+				}
+			}
+			for k, v := range fault.Header {
+				w.Header()[k] = v
+			}
+			respond(w, fault.Status, map[string]string{"message": http.StatusText(fault.Status)})
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "rate_limit" {
+		core := map[string]int64{"limit": rateLimit, "used": int64(s.requests), "remaining": int64(rateLimit - s.requests),
+			"reset": time.Now().Add(time.Hour).Unix()}
+		respond(w, http.StatusOK, map[string]interface{}{"resources": map[string]interface{}{"core": core}})
+		return
+	}
+	if len(parts) < 3 || parts[0] != "repos" {
+		respond(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	repo := s.repo(parts[1] + "/" + parts[2])
+	s.route(w, r, parts[1]+"/"+parts[2], repo, parts[3:])
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, name string, repo *Repo, parts []string) {
+	notFound := func() { respond(w, http.StatusNotFound, map[string]string{"message": "Not Found"}) }
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		respond(w, http.StatusOK, map[string]interface{}{
+			"full_name":         name,
+			"private":           false,
+			"visibility":        "public",
+			"has_issues":        repo.HasIssues,
+			"size":              0,
+			"open_issues_count": len(repo.Issues),
+			"permissions":       map[string]bool{"admin": true, "push": true, "pull": true},
+		})
+
+	case len(parts) == 1 && parts[0] == "issues" && r.Method == http.MethodPost:
+		payload := struct {
+			Title     string   `json:"title"`
+			Body      string   `json:"body"`
+			Labels    []string `json:"labels"`
+			Assignees []string `json:"assignees"`
+			Milestone int      `json:"milestone"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		if payload.Title == "" {
+			respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "title is missing"})
+			return
+		}
+		issue := s.newIssue(repo, payload.Title, payload.Body)
+		issue.Labels, issue.Assignees, issue.Milestone = payload.Labels, payload.Assignees, payload.Milestone
+		for _, label := range payload.Labels {
+			if _, ok := repo.Labels[label]; !ok {
+				repo.Labels[label] = &Label{Name: label, Color: "ededed"}
+			}
+		}
+		respond(w, http.StatusCreated, issueJSON(name, issue))
+
+	case len(parts) == 1 && parts[0] == "issues" && r.Method == http.MethodGet:
+		state := r.URL.Query().Get("state")
+		items := []interface{}{}
+		for _, issue := range repo.Issues {
+			if state == "all" || issue.State == state || (state == "" && issue.State == "open") {
+				items = append(items, issueJSON(name, issue))
+			}
+		}
+		if r.URL.Query().Get("direction") == "desc" {
+			for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+				items[i], items[j] = items[j], items[i]
+			}
+		}
+		respond(w, http.StatusOK, paginate(r, items))
+
+	case len(parts) == 2 && parts[0] == "issues" && parts[1] != "comments":
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			respond(w, http.StatusOK, issueJSON(name, issue))
+		case http.MethodPatch:
+			payload := map[string]interface{}{}
+			if !decode(w, r, &payload) {
+				return
+			}
+			if v, ok := payload["title"].(string); ok {
+				issue.Title = v
+			}
+			if v, ok := payload["body"].(string); ok {
+				issue.Body = v
+			}
+			if v, ok := payload["state"].(string); ok {
+				issue.State = v
+			}
+			if v, ok := payload["labels"].([]interface{}); ok {
+				issue.Labels = []string{}
+				for _, label := range v {
+					issue.Labels = append(issue.Labels, label.(string))
+				}
+			}
+			respond(w, http.StatusOK, issueJSON(name, issue))
+		default:
+			notFound()
+		}
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "comments":
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			comment := &Comment{}
+			if !decode(w, r, comment) {
+				return
+			}
+			s.nextId++
+			comment.Id = s.nextId
+			issue.Comments = append(issue.Comments, comment)
+			respond(w, http.StatusCreated, comment)
+		case http.MethodGet:
+			items := []interface{}{}
+			for _, comment := range issue.Comments {
+				items = append(items, comment)
+			}
+			respond(w, http.StatusOK, paginate(r, items))
+		default:
+			notFound()
+		}
+
+	case len(parts) == 4 && parts[0] == "issues" && parts[2] == "dependencies" && parts[3] == "blocked_by" && r.Method == http.MethodGet:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		respond(w, http.StatusOK, paginate(r, issuesJSON(name, repo, issue.BlockedBy)))
+
+	case len(parts) == 4 && parts[0] == "issues" && parts[2] == "dependencies" && parts[3] == "blocked_by" && r.Method == http.MethodPost:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		payload := struct {
+			IssueId int64 `json:"issue_id"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		for _, id := range issue.BlockedBy {
+			if id == payload.IssueId {
+				respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+				return
+			}
+		}
+		issue.BlockedBy = append(issue.BlockedBy, payload.IssueId)
+		respond(w, http.StatusCreated, issueJSON(name, issue))
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "sub_issues" && r.Method == http.MethodGet:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		respond(w, http.StatusOK, paginate(r, issuesJSON(name, repo, issue.SubIssues)))
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "sub_issues" && r.Method == http.MethodPost:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		payload := struct {
+			SubIssueId int64 `json:"sub_issue_id"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		// an issue can have a single parent
+		for _, other := range repo.Issues {
+			for _, id := range other.SubIssues {
+				if id == payload.SubIssueId {
+					respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+					return
+				}
+			}
+		}
+		issue.SubIssues = append(issue.SubIssues, payload.SubIssueId)
+		respond(w, http.StatusCreated, issueJSON(name, issue))
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "reactions" && r.Method == http.MethodPost:
+		issue := findIssue(repo, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		react(w, r, &issue.Reactions)
+
+	case len(parts) == 4 && parts[0] == "issues" && parts[1] == "comments" && parts[3] == "reactions" && r.Method == http.MethodPost:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for _, issue := range repo.Issues {
+			for _, comment := range issue.Comments {
+				if comment.Id == id {
+					react(w, r, &comment.Reactions)
+					return
+				}
+			}
+		}
+		notFound()
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[1] == "comments" && r.Method == http.MethodPatch:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for _, issue := range repo.Issues {
+			for _, comment := range issue.Comments {
+				if comment.Id == id {
+					payload := &Comment{}
+					if !decode(w, r, payload) {
+						return
+					}
+					comment.Body = payload.Body
+					respond(w, http.StatusOK, comment)
+					return
+				}
+			}
+		}
+		notFound()
+
+	case len(parts) == 1 && parts[0] == "labels":
+		switch r.Method {
+		case http.MethodGet:
+			items := []interface{}{}
+			for _, label := range repo.Labels {
+				items = append(items, label)
+			}
+			respond(w, http.StatusOK, paginate(r, items))
+		case http.MethodPost:
+			label := &Label{}
+			if !decode(w, r, label) {
+				return
+			}
+			if _, ok := repo.Labels[label.Name]; ok {
+				respond(w, http.StatusUnprocessableEntity, map[string]string{"message": "Validation Failed"})
+				return
+			}
+			repo.Labels[label.Name] = label
+			respond(w, http.StatusCreated, label)
+		default:
+			notFound()
+		}
+
+	case len(parts) == 2 && parts[0] == "labels" && r.Method == http.MethodGet:
+		if label, ok := repo.Labels[parts[1]]; ok {
+			respond(w, http.StatusOK, label)
+		} else {
+			notFound()
+		}
+
+	case len(parts) == 1 && parts[0] == "milestones":
+		switch r.Method {
+		case http.MethodGet:
+			items := []interface{}{}
+			for _, milestone := range repo.Milestones {
+				items = append(items, milestone)
+			}
+			respond(w, http.StatusOK, paginate(r, items))
+		case http.MethodPost:
+			milestone := &Milestone{}
+			if !decode(w, r, milestone) {
+				return
+			}
+			milestone.Number = len(repo.Milestones) + 1
+			if milestone.State == "" {
+				milestone.State = "open"
+			}
+			repo.Milestones = append(repo.Milestones, milestone)
+			respond(w, http.StatusCreated, milestone)
+		default:
+			notFound()
+		}
+
+	case len(parts) == 2 && parts[0] == "assignees" && r.Method == http.MethodGet:
+		for _, user := range repo.Collaborators {
+			if user == parts[1] {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		notFound()
+
+	case len(parts) == 2 && parts[0] == "import" && parts[1] == "issues" && r.Method == http.MethodPost:
+		// the issue import API creates the issue along with its comments
+		payload := struct {
+			Issue struct {
+				Title  string   `json:"title"`
+				Body   string   `json:"body"`
+				Closed bool     `json:"closed"`
+				Labels []string `json:"labels"`
+			} `json:"issue"`
+			Comments []struct {
+				Body string `json:"body"`
+			} `json:"comments"`
+		}{}
+		if !decode(w, r, &payload) {
+			return
+		}
+		issue := s.newIssue(repo, payload.Issue.Title, payload.Issue.Body)
+		issue.Labels = payload.Issue.Labels
+		if payload.Issue.Closed {
+			issue.State = "closed"
+		}
+		for _, c := range payload.Comments {
+			s.nextId++
+			issue.Comments = append(issue.Comments, &Comment{Id: s.nextId, Body: c.Body})
+		}
+		respond(w, http.StatusAccepted, importJSON(s.URL, name, issue))
+
+	case len(parts) == 3 && parts[0] == "import" && parts[1] == "issues" && r.Method == http.MethodGet:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		for _, issue := range repo.Issues {
+			if issue.Id == id {
+				respond(w, http.StatusOK, importJSON(s.URL, name, issue))
+				return
+			}
+		}
+		notFound()
+
+	default:
+		notFound()
+	}
+}
+
+func findIssue(repo *Repo, number string) *Issue {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil
+	}
+	for _, issue := range repo.Issues {
+		if issue.Number == n {
+			return issue
+		}
+	}
+	return nil
+}
+
+// issuesJSON returns the issues of repo with the ids
+func issuesJSON(name string, repo *Repo, ids []int64) []interface{} {
+	items := []interface{}{}
+	for _, id := range ids {
+		for _, issue := range repo.Issues {
+			if issue.Id == id {
+				items = append(items, issueJSON(name, issue))
+			}
+		}
+	}
+	return items
+}
+
+func issueJSON(repo string, issue *Issue) map[string]interface{} {
+	labels := []map[string]string{}
+	for _, label := range issue.Labels {
+		labels = append(labels, map[string]string{"name": label})
+	}
+	assignees := []map[string]string{}
+	for _, assignee := range issue.Assignees {
+		assignees = append(assignees, map[string]string{"login": assignee})
+	}
+	m := map[string]interface{}{
+		"id":        issue.Id,
+		"number":    issue.Number,
+		"title":     issue.Title,
+		"body":      issue.Body,
+		"state":     issue.State,
+		"labels":    labels,
+		"assignees": assignees,
+		"comments":  len(issue.Comments),
+		"html_url":  fmt.Sprintf("https://github.com/%s/issues/%d", repo, issue.Number),
+	}
+	if issue.IsPullRequest {
+		m["pull_request"] = map[string]string{}
+	}
+	return m
+}
+
+func importJSON(endpoint, repo string, issue *Issue) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        issue.Id,
+		"status":    "imported",
+		"url":       fmt.Sprintf("%s/repos/%s/import/issues/%d", endpoint, repo, issue.Id),
+		"issue_url": fmt.Sprintf("%s/repos/%s/issues/%d", endpoint, repo, issue.Number),
+	}
+}
+
+func paginate(r *http.Request, items []interface{}) []interface{} {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 30
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	if start >= len(items) {
+		return []interface{}{}
+	}
+	end := start + perPage
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// react adds the reaction of the request to reactions unless it already exists
+func react(w http.ResponseWriter, r *http.Request, reactions *[]string) {
+	payload := struct {
+		Content string `json:"content"`
+	}{}
+	if !decode(w, r, &payload) {
+		return
+	}
+	for _, content := range *reactions {
+		if content == payload.Content {
+			respond(w, http.StatusOK, payload)
+			return
+		}
+	}
+	*reactions = append(*reactions, payload.Content)
+	respond(w, http.StatusCreated, payload)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"message": "Problems parsing JSON"})
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
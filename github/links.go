@@ -0,0 +1,165 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// marks the section (or comment) that lists the related issues and tasks
+const linksMarker = "<!-- gl2gh:links -->"
+
+// Link is a relation of an issue to another issue
+type Link struct {
+	// the type of the gitlab link (e.g. relates_to)
+	Type string
+	// the github number of the linked issue (0 if it was not migrated)
+	Number int
+	// the gitlab reference of the linked issue (e.g. #4 or group/other#4) if Number is 0
+	Reference string
+}
+
+var linkDescriptions = map[string]string{
+	"relates_to":    "relates to",
+	"blocks":        "blocks",
+	"is_blocked_by": "is blocked by",
+}
+
+// LinksSection renders the links and the tasks (listed as a task list)
+// as a markdown section (including its marker)
+func LinksSection(links []Link, tasks []Task) string {
+	lines := []string{linksMarker}
+	if len(links) > 0 {
+		lines = append(lines, "### Related issues", "")
+	}
+	for _, link := range links {
+		description, ok := linkDescriptions[link.Type]
+		if !ok {
+			description = strings.ReplaceAll(link.Type, "_", " ")
+		}
+		if link.Number > 0 {
+			lines = append(lines, fmt.Sprintf("- %s #%d", description, link.Number))
+		} else {
+			lines = append(lines, fmt.Sprintf("- %s gitlab issue `%s` (not migrated)", description, link.Reference))
+		}
+	}
+	if len(tasks) > 0 {
+		if len(links) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "### Tasks", "")
+	}
+	for _, task := range tasks {
+		check := " "
+		if task.Closed {
+			check = "x"
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] #%d", check, task.Number))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// IsLinksSection returns true if the body (of a comment) is a section created by LinksSection
+func IsLinksSection(body string) bool {
+	return strings.HasPrefix(body, linksMarker)
+}
+
+// WithLinks returns the body with its links section (if any) replaced by section
+// The section is kept at the end of the body.
+func WithLinks(body, section string) string {
+	if i := strings.Index(body, linksMarker); i >= 0 {
+		body = strings.TrimRight(body[:i], "\n")
+	}
+	return body + "\n\n" + section
+}
+
+// KeepLinks returns the body with the links section of live (if any),
+// so that re-rendering the body of an issue does not drop its related issues
+func KeepLinks(body, live string) string {
+	if i := strings.Index(live, linksMarker); i >= 0 {
+		return WithLinks(body, live[i:])
+	}
+	return body
+}
+
+// ErrDependenciesUnsupported is returned by AddBlockedBy when the server does not support
+// issue dependencies (e.g. older versions of github enterprise)
+var ErrDependenciesUnsupported = errors.New("issue dependencies are not supported")
+
+// Dependencies is implemented by the targets that support issue dependencies
+type Dependencies interface {
+	// AddBlockedBy marks the issue number as blocked by the issue blocker
+	// (it is not an error if the dependency already exists)
+	AddBlockedBy(ctx context.Context, repo string, number, blocker int) error
+}
+
+func (c *Client) AddBlockedBy(ctx context.Context, repo string, number, blocker int) error {
+	// dependencies refer to the (database) id of the blocking issue
+	issue, err := c.GetIssue(ctx, repo, blocker)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/%d/dependencies/blocked_by", (&Issue{}).Path(repo), number)
+	// github responds with the same validation error to existing dependencies
+	// and to invalid ones (e.g. cycles), so the existing ones are looked up first
+	ids, err := c.issueIds(ctx, path)
+	if isNotFound(err) {
+		return fmt.Errorf("error listing the dependencies of #%d: %w", number, ErrDependenciesUnsupported)
+	}
+	if err != nil {
+		return fmt.Errorf("error listing the dependencies of #%d: %w", number, err)
+	}
+	for _, id := range ids {
+		if id == issue.Id {
+			return nil
+		}
+	}
+
+	payload, err := json.Marshal(map[string]int64{"issue_id": issue.Id})
+	if err != nil {
+		return fmt.Errorf("error serializing dependency: %v", err)
+	}
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(path), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	if _, err := c.Do(req, http.StatusCreated); err != nil {
+		return fmt.Errorf("error marking issue #%d as blocked by #%d: %w", number, blocker, err)
+	}
+	return nil
+}
+
+// issueIds returns the (database) ids of the issues listed at path (e.g. the dependencies of an issue)
+func (c *Client) issueIds(ctx context.Context, path string) ([]int64, error) {
+	ids := []int64{}
+	for page := 1; ; page++ {
+		req, err := c.NewRequest(ctx, http.MethodGet, c.URL(fmt.Sprintf("%s?per_page=%d&page=%d", path, perPage, page)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare request: %w", err)
+		}
+		resBody, err := c.Do(req, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		batch := []struct {
+			Id int64 `json:"id"`
+		}{}
+		if err := json.Unmarshal(resBody, &batch); err != nil {
+			return nil, fmt.Errorf("error parsing issues response body: %v", err)
+		}
+		for _, i := range batch {
+			ids = append(ids, i.Id)
+		}
+		if len(batch) < perPage {
+			return ids, nil
+		}
+	}
+}
+
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
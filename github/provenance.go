@@ -0,0 +1,61 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Provenance identifies the gitlab item that a github issue or comment was created from
+type Provenance struct {
+	// the path of the gitlab project (e.g. group/project)
+	Project string
+	Iid     int
+	Note    int
+}
+
+var provenanceRe = regexp.MustCompile(`<!-- gl2gh((?: \w+=\S+)*) -->`)
+var provenanceAttrRe = regexp.MustCompile(`(\w+)=(\S+)`)
+
+// Marker returns an HTML comment that is invisible in the rendered markdown
+func (p Provenance) Marker() string {
+	s := fmt.Sprintf("iid=%d", p.Iid)
+	if p.Project != "" {
+		s = fmt.Sprintf("project=%s %s", p.Project, s)
+	}
+	if p.Note > 0 {
+		s += fmt.Sprintf(" note=%d", p.Note)
+	}
+	return fmt.Sprintf("<!-- gl2gh %s -->", s)
+}
+
+// ParseProvenance finds the provenance marker in the body of an issue or comment
+// The marker is appended to the body, so the last one wins over any that is quoted in the text.
+func ParseProvenance(body string) (Provenance, bool) {
+	p := Provenance{}
+	matches := provenanceRe.FindAllStringSubmatch(body, -1)
+	if len(matches) == 0 {
+		return p, false
+	}
+	m := matches[len(matches)-1]
+	for _, attr := range provenanceAttrRe.FindAllStringSubmatch(m[1], -1) {
+		switch attr[1] {
+		case "project":
+			p.Project = attr[2]
+		case "iid":
+			p.Iid, _ = strconv.Atoi(attr[2])
+		case "note":
+			p.Note, _ = strconv.Atoi(attr[2])
+		}
+	}
+	return p, p.Iid > 0
+}
+
+// Matches returns true if the marker refers to an item of project
+func (p Provenance) Matches(project string) bool {
+	return p.Project == project
+}
+
+func withMarker(body string, p Provenance) string {
+	return body + "\n\n" + p.Marker()
+}
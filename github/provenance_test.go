@@ -0,0 +1,29 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseProvenance(t *testing.T) {
+	kases := []struct {
+		body     string
+		expected Provenance
+		found    bool
+	}{
+		{withMarker("a comment", Provenance{Iid: 12, Note: 345}), Provenance{Iid: 12, Note: 345}, true},
+		{withMarker("an issue", Provenance{Iid: 7}), Provenance{Iid: 7}, true},
+		{withMarker("an issue", Provenance{Project: "group/sub/proj", Iid: 7}), Provenance{Project: "group/sub/proj", Iid: 7}, true},
+		// a comment that quotes the body of another migrated item
+		{withMarker("> quoted\n> <!-- gl2gh iid=3 note=4 -->\n\nreply", Provenance{Iid: 12, Note: 345}), Provenance{Iid: 12, Note: 345}, true},
+		{"no marker here", Provenance{}, false},
+		{"<!-- some other comment -->", Provenance{}, false},
+	}
+
+	for _, kase := range kases {
+		p, found := ParseProvenance(kase.body)
+		assert.Equal(t, kase.found, found)
+		assert.Equal(t, kase.expected, p)
+	}
+}
@@ -0,0 +1,30 @@
+package github
+
+import "github.com/kkentzo/gl-to-gh/gitlab"
+
+// the github reactions indexed by the names of the equivalent gitlab award emoji
+var reactions = map[string]string{
+	"thumbsup":   "+1",
+	"thumbsdown": "-1",
+	"laughing":   "laugh",
+	"confused":   "confused",
+	"heart":      "heart",
+	"tada":       "hooray",
+	"rocket":     "rocket",
+	"eyes":       "eyes",
+}
+
+// Reactions returns the github reactions of the award emoji that github supports
+// Every reaction appears once (a user can react only once with each reaction),
+// in the order of its first award.
+func Reactions(awards []*gitlab.AwardEmoji) []string {
+	seen := map[string]bool{}
+	contents := []string{}
+	for _, award := range awards {
+		if content, ok := reactions[award.Name]; ok && !seen[content] {
+			seen[content] = true
+			contents = append(contents, content)
+		}
+	}
+	return contents
+}
@@ -0,0 +1,112 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Repo is the metadata of a github repository
+type Repo struct {
+	FullName    string `json:"full_name"`
+	Private     bool   `json:"private"`
+	Visibility  string `json:"visibility"`
+	HasIssues   bool   `json:"has_issues"`
+	Permissions struct {
+		Admin bool `json:"admin"`
+		Push  bool `json:"push"`
+		Pull  bool `json:"pull"`
+	} `json:"permissions"`
+}
+
+func GetRepo(ctx context.Context, client *Client, repo string) (*Repo, error) {
+	resp, body, err := client.Probe(ctx, "/repos/"+repo)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Expected: http.StatusOK, Header: resp.Header, Body: body}
+	}
+	r := &Repo{}
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, fmt.Errorf("error parsing repo response body: %v", err)
+	}
+	return r, nil
+}
+
+// LastIssueNumber returns the highest number of the repo's issues and pull requests
+// (zero if there are none), after which github numbers the new issues
+func LastIssueNumber(ctx context.Context, client *Client, repo string) (int, error) {
+	resp, body, err := client.Probe(ctx, fmt.Sprintf("/repos/%s/issues?state=all&sort=created&direction=desc&per_page=1", repo))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, &StatusError{StatusCode: resp.StatusCode, Expected: http.StatusOK, Header: resp.Header, Body: body}
+	}
+	issues := []struct {
+		Number int `json:"number"`
+	}{}
+	if err := json.Unmarshal(body, &issues); err != nil {
+		return 0, fmt.Errorf("error parsing issues response body: %v", err)
+	}
+	if len(issues) == 0 {
+		return 0, nil
+	}
+	return issues[0].Number, nil
+}
+
+// Scopes returns the OAuth scopes of the client's token
+// ok is false if the token does not have OAuth scopes (e.g. fine-grained or app tokens)
+func Scopes(ctx context.Context, client *Client) (scopes []string, ok bool, err error) {
+	resp, body, err := client.Probe(ctx, "/rate_limit")
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, &StatusError{StatusCode: resp.StatusCode, Expected: http.StatusOK, Header: resp.Header, Body: body}
+	}
+	if _, ok := resp.Header["X-Oauth-Scopes"]; !ok {
+		return nil, false, nil
+	}
+	scopes = []string{}
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true, nil
+}
+
+// IsAssignable returns true if issues of the repo can be assigned to user
+func IsAssignable(ctx context.Context, client *Client, repo, user string) (bool, error) {
+	return exists(ctx, client, fmt.Sprintf("/repos/%s/assignees/%s", repo, url.PathEscape(user)), http.StatusNoContent)
+}
+
+// LabelExists returns true if the repo has a label with the specified name
+func LabelExists(ctx context.Context, client *Client, repo, name string) (bool, error) {
+	return exists(ctx, client, fmt.Sprintf("/repos/%s/labels/%s", repo, url.PathEscape(name)), http.StatusOK)
+}
+
+func exists(ctx context.Context, client *Client, path string, found int) (bool, error) {
+	resp, body, err := client.Probe(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	switch resp.StatusCode {
+	case found:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, &StatusError{StatusCode: resp.StatusCode, Expected: found, Header: resp.Header, Body: body}
+}
+
+// IssueURL returns the web URL of the issue number of the repo
+// on the github instance at webURL (e.g. https://github.com)
+func IssueURL(webURL, repo string, number int) string {
+	return fmt.Sprintf("%s/%s/issues/%d", strings.TrimSuffix(webURL, "/"), repo, number)
+}
@@ -1,52 +1,97 @@
 package github
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
+	"log"
 
 	"github.com/kkentzo/gl-to-gh/gitlab"
 )
 
+// ClosedLabel marks the issues that were closed in gitlab
+// (issues are not created closed, so that they can be imported in a single request)
+const ClosedLabel = "closed"
+
 type Issue struct {
 	Title     string   `json:"title"`
 	Body      string   `json:"body"`
 	Assignees []string `json:"assignees"`
 	Labels    []string `json:"labels"`
+	Number    int      `json:"-"`
+	// the database id of the issue (known only for fetched issues)
+	Id    int64  `json:"-"`
+	State string `json:"-"`
+	// reactions (e.g. "+1") that are added right after the issue is created
+	Reactions []string `json:"-"`
 	comments  []*Comment
+	// the body that will replace Body right after the issue is created
+	deferredBody string
 }
 
-func New(glIssue *gitlab.Issue, mappings map[int]string, labels []string, replPatterns map[string]string) (*Issue, error) {
-	body, err := glIssue.Convert(mappings, replPatterns)
+func New(glIssue *gitlab.Issue, labels []string, opts gitlab.Options) (*Issue, error) {
+	provenance := Provenance{Project: opts.Project, Iid: glIssue.Id}
+	body, err := glIssue.Convert(opts)
 	if err != nil {
 		return nil, err
 	}
+	body = withMarker(body, provenance)
+	deferredBody := ""
+	if opts.Attribution == gitlab.AttributionDeferred {
+		final := opts
+		final.Attribution = gitlab.AttributionMention
+		if deferredBody, err = glIssue.Convert(final); err != nil {
+			return nil, err
+		}
+		if deferredBody = withMarker(deferredBody, provenance); deferredBody == body {
+			deferredBody = ""
+		}
+	}
+	state := "open"
 	if glIssue.IsClosed() {
-		labels = append(labels, "closed")
+		labels = append(labels, ClosedLabel)
+		state = "closed"
 	}
 
 	issue := &Issue{
 		Title:     glIssue.Title,
 		Body:      body,
 		Labels:    labels,
-		Assignees: FindAssignees(glIssue, mappings),
+		Assignees: FindAssignees(glIssue, opts.Mappings),
+		State:     state,
+		Reactions: Reactions(glIssue.AwardEmoji),
 		comments:  []*Comment{},
+
+		deferredBody: deferredBody,
 	}
 	for _, glComment := range glIssue.Comments {
-		body, err = glComment.Convert(replPatterns)
+		comment, err := NewComment(glComment, glIssue.Id, opts)
 		if err != nil {
 			return nil, err
 		}
-		comment := &Comment{Body: body}
 		issue.comments = append(issue.comments, comment)
 	}
 	return issue, nil
 }
 
-func NewPlaceholder(labels []string) *Issue {
+// NewComment converts the note of the gitlab issue iid to a comment
+func NewComment(glComment *gitlab.Comment, iid int, opts gitlab.Options) (*Comment, error) {
+	body, err := glComment.Convert(opts)
+	if err != nil {
+		return nil, err
+	}
+	comment := &Comment{Body: body, Reactions: Reactions(glComment.AwardEmoji)}
+	if glComment.Id > 0 {
+		comment.Body = withMarker(body, Provenance{Project: opts.Project, Iid: iid, Note: glComment.Id})
+	}
+	return comment, nil
+}
+
+// NewPlaceholder returns an issue that stands in for the deleted gitlab issue identified by provenance
+func NewPlaceholder(labels []string, provenance Provenance) *Issue {
 	return &Issue{
-		Title:     "[DELETED GITLAB ISSUE]",
-		Body:      "This issue was created during the import of gitlab issues in order to preserve the ID ordering of gitlab issue IDs. In reality, it represents a deleted gitlab issue.",
+		Title: "[DELETED GITLAB ISSUE]",
+		Body: withMarker("This issue was created during the import of gitlab issues in order to preserve the ID ordering of gitlab issue IDs. In reality, it represents a deleted gitlab issue.",
+			provenance),
 		Assignees: []string{},
 		Labels:    labels,
 		comments:  []*Comment{},
@@ -71,55 +116,82 @@ func (issue *Issue) Comments() []*Comment {
 	return issue.comments
 }
 
-func (issue *Issue) Post(client *Client, repo string) error {
-	// serialize the issue
-	body, err := json.Marshal(issue)
-	if err != nil {
-		return fmt.Errorf("failed to serialize issue: %v\nThe problematic issue is:\n%v\n", err, issue)
+// FinalBody returns the body of the issue as it will be after its creation
+// (i.e. including any deferred mentions)
+func (issue *Issue) FinalBody() string {
+	if issue.deferredBody != "" {
+		return issue.deferredBody
 	}
-	// prepare the request
-	req, err := client.NewRequest(http.MethodPost, urljoin(apiEndpoint, issue.Path(repo)), body)
-	if err != nil {
-		return fmt.Errorf("error preparing the request: %v", err)
+	return issue.Body
+}
+
+// Provenance returns the gitlab issue that the issue was created from (if known)
+func (issue *Issue) Provenance() (Provenance, bool) {
+	return ParseProvenance(issue.Body)
+}
+
+// Post creates the issue in the target repo and sets its number
+func (issue *Issue) Post(ctx context.Context, target Target, repo string) error {
+	if err := target.CreateIssue(ctx, repo, issue); err != nil {
+		return err
 	}
-	resBody, err := client.Do(req, http.StatusCreated)
-	if err != nil {
-		return fmt.Errorf("request failed: %v\nResponse Body=%s", err, string(resBody))
+	if issue.deferredBody != "" {
+		if err := issue.Edit(ctx, target, repo, issue.deferredBody); err != nil {
+			return fmt.Errorf("failed to apply deferred mentions: %w", err)
+		}
 	}
-
-	// figure out the URL for posting the comments
-	response := struct {
-		CommentsURL string `json:"comments_url"`
-	}{}
-	if err := json.Unmarshal(resBody, &response); err != nil {
-		return fmt.Errorf("error parsing issue response body: %v", err)
+	// the issue exists by now, so a failed reaction must not make the caller lose track of it
+	for _, content := range issue.Reactions {
+		if err := target.CreateIssueReaction(ctx, repo, issue.Number, content); err != nil {
+			log.Printf("%s: failed to add reaction %s to issue #%d: %v", repo, content, issue.Number, err)
+		}
 	}
+	return nil
+}
 
-	return err
+// Edit replaces the body of the (already created) issue
+func (issue *Issue) Edit(ctx context.Context, target Target, repo string, body string) error {
+	if err := target.UpdateIssue(ctx, repo, issue.Number, IssueUpdate{Body: body}); err != nil {
+		return err
+	}
+	issue.Body = body
+	return nil
 }
 
 type Comment struct {
+	Id   int64  `json:"-"`
 	Body string `json:"body"`
+	// reactions (e.g. "+1") that are added right after the comment is created
+	Reactions []string `json:"-"`
+}
+
+// Edit replaces the body of the (already created) comment
+func (comment *Comment) Edit(ctx context.Context, target Target, repo string, body string) error {
+	if err := target.EditComment(ctx, repo, comment.Id, body); err != nil {
+		return err
+	}
+	comment.Body = body
+	return nil
+}
+
+// Provenance returns the gitlab note that the comment was created from (if known)
+func (comment *Comment) Provenance() (Provenance, bool) {
+	return ParseProvenance(comment.Body)
 }
 
 func (comment *Comment) Path(repo string, issueId int) string {
 	return fmt.Sprintf("/repos/%s/issues/%d/comments", repo, issueId)
 }
 
-func (comment *Comment) Post(client *Client, repo string, issueId int) error {
-	// serialize the comment
-	body, err := json.Marshal(comment)
-	if err != nil {
-		return fmt.Errorf("error serializing comment: %v\nThe problematic comment is:\n%v\n", err, comment)
+// Post adds the comment to the issue of the target repo and sets its ID
+func (comment *Comment) Post(ctx context.Context, target Target, repo string, issueId int) error {
+	if err := target.CreateComment(ctx, repo, issueId, comment); err != nil {
+		return err
 	}
-	// post the comment
-	req, err := client.NewRequest(http.MethodPost, urljoin(apiEndpoint, comment.Path(repo, issueId)), body)
-	if err != nil {
-		return fmt.Errorf("failed to prepare request: %v", err)
-	}
-	if _, err = client.Do(req, http.StatusCreated); err != nil {
-		return fmt.Errorf("error posting comment: %v", err)
+	for _, content := range comment.Reactions {
+		if err := target.CreateCommentReaction(ctx, repo, comment.Id, content); err != nil {
+			log.Printf("%s: failed to add reaction %s to comment %d: %v", repo, content, comment.Id, err)
+		}
 	}
-
 	return nil
 }
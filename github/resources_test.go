@@ -0,0 +1,21 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reactions(t *testing.T) {
+	awards := []*gitlab.AwardEmoji{
+		{Name: "tada", UserId: 1},
+		{Name: "thumbsup", UserId: 1},
+		{Name: "thumbsup", UserId: 2},
+		{Name: "party_parrot", UserId: 2},
+		{Name: "thumbsdown", UserId: 3},
+	}
+	assert.Equal(t, []string{"hooray", "+1", "-1"}, Reactions(awards))
+	assert.Empty(t, Reactions(nil))
+	assert.Equal(t, ":thumbsup: 2, :party_parrot: 1, :tada: 1, :thumbsdown: 1", gitlab.SummarizeAwards(awards))
+}
@@ -0,0 +1,266 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// the REST implementation of Target
+
+func (c *Client) CreateIssue(ctx context.Context, repo string, issue *Issue) error {
+	// serialize the issue
+	body, err := json.Marshal(issue)
+	if err != nil {
+		return fmt.Errorf("failed to serialize issue: %v\nThe problematic issue is:\n%v\n", err, issue)
+	}
+	// prepare the request
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(issue.Path(repo)), body)
+	if err != nil {
+		return fmt.Errorf("error preparing the request: %w", err)
+	}
+	resBody, err := c.Do(req, http.StatusCreated)
+	if err != nil {
+		return fmt.Errorf("request failed: %w\nResponse Body=%s", err, string(resBody))
+	}
+
+	// figure out the number of the created issue
+	response := struct {
+		Number int `json:"number"`
+	}{}
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return fmt.Errorf("error parsing issue response body: %v", err)
+	}
+	issue.Number = response.Number
+	return nil
+}
+
+func (c *Client) UpdateIssue(ctx context.Context, repo string, number int, update IssueUpdate) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to serialize issue update: %v", err)
+	}
+	path := fmt.Sprintf("%s/%d", (&Issue{}).Path(repo), number)
+	req, err := c.NewRequest(ctx, http.MethodPatch, c.URL(path), payload)
+	if err != nil {
+		return fmt.Errorf("error preparing the request: %w", err)
+	}
+	if resBody, err := c.Do(req, http.StatusOK); err != nil {
+		return fmt.Errorf("request failed: %w\nResponse Body=%s", err, string(resBody))
+	}
+	return nil
+}
+
+// GetIssue fetches the github issue with the specified number
+func (c *Client) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	path := fmt.Sprintf("%s/%d", (&Issue{}).Path(repo), number)
+	req, err := c.NewRequest(ctx, http.MethodGet, c.URL(path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing the request: %w", err)
+	}
+	resBody, err := c.Do(req, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching issue #%d: %w", number, err)
+	}
+	response := &restIssue{}
+	if err := json.Unmarshal(resBody, response); err != nil {
+		return nil, fmt.Errorf("error parsing issue response body: %v", err)
+	}
+	return response.convert(), nil
+}
+
+// restIssue is an issue as returned by the API
+type restIssue struct {
+	Id     int64  `json:"id"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	State  string `json:"state"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest json.RawMessage `json:"pull_request"`
+}
+
+func (i *restIssue) convert() *Issue {
+	issue := &Issue{Id: i.Id, Number: i.Number, Title: i.Title, Body: i.Body, State: i.State, Labels: []string{}}
+	for _, label := range i.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue
+}
+
+// ListIssues returns all the issues (but not pull requests) of the github repo
+func (c *Client) ListIssues(ctx context.Context, repo string) ([]*Issue, error) {
+	issues := []*Issue{}
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s?state=all&sort=created&direction=asc&per_page=%d&page=%d", (&Issue{}).Path(repo), perPage, page)
+		req, err := c.NewRequest(ctx, http.MethodGet, c.URL(path), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error preparing the request: %w", err)
+		}
+		resBody, err := c.Do(req, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("error listing issues: %w", err)
+		}
+		batch := []*restIssue{}
+		if err := json.Unmarshal(resBody, &batch); err != nil {
+			return nil, fmt.Errorf("error parsing issues response body: %v", err)
+		}
+		for _, i := range batch {
+			if i.PullRequest != nil {
+				continue
+			}
+			issues = append(issues, i.convert())
+		}
+		if len(batch) < perPage {
+			return issues, nil
+		}
+	}
+}
+
+func (c *Client) CreateComment(ctx context.Context, repo string, number int, comment *Comment) error {
+	// serialize the comment
+	body, err := json.Marshal(comment)
+	if err != nil {
+		return fmt.Errorf("error serializing comment: %v\nThe problematic comment is:\n%v\n", err, comment)
+	}
+	// post the comment
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(comment.Path(repo, number)), body)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	resBody, err := c.Do(req, http.StatusCreated)
+	if err != nil {
+		return fmt.Errorf("error posting comment: %w", err)
+	}
+
+	response := struct {
+		Id int64 `json:"id"`
+	}{}
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return fmt.Errorf("error parsing comment response body: %v", err)
+	}
+	comment.Id = response.Id
+	return nil
+}
+
+func (c *Client) EditComment(ctx context.Context, repo string, id int64, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("error serializing comment body: %v", err)
+	}
+	path := fmt.Sprintf("/repos/%s/issues/comments/%d", repo, id)
+	req, err := c.NewRequest(ctx, http.MethodPatch, c.URL(path), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	if _, err := c.Do(req, http.StatusOK); err != nil {
+		return fmt.Errorf("error editing comment: %w", err)
+	}
+	return nil
+}
+
+// ListComments returns all the comments of the github issue with the specified number
+func (c *Client) ListComments(ctx context.Context, repo string, number int) ([]*Comment, error) {
+	comments := []*Comment{}
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s?per_page=%d&page=%d", (&Comment{}).Path(repo, number), perPage, page)
+		req, err := c.NewRequest(ctx, http.MethodGet, c.URL(path), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare request: %w", err)
+		}
+		resBody, err := c.Do(req, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("error listing comments: %w", err)
+		}
+		batch := []struct {
+			Id   int64  `json:"id"`
+			Body string `json:"body"`
+		}{}
+		if err := json.Unmarshal(resBody, &batch); err != nil {
+			return nil, fmt.Errorf("error parsing comments response body: %v", err)
+		}
+		for _, c := range batch {
+			comments = append(comments, &Comment{Id: c.Id, Body: c.Body})
+		}
+		if len(batch) < perPage {
+			return comments, nil
+		}
+	}
+}
+
+func (c *Client) CreateIssueReaction(ctx context.Context, repo string, number int, content string) error {
+	return c.createReaction(ctx, fmt.Sprintf("%s/%d/reactions", (&Issue{}).Path(repo), number), content)
+}
+
+func (c *Client) CreateCommentReaction(ctx context.Context, repo string, id int64, content string) error {
+	return c.createReaction(ctx, fmt.Sprintf("/repos/%s/issues/comments/%d/reactions", repo, id), content)
+}
+
+func (c *Client) createReaction(ctx context.Context, path, content string) error {
+	payload, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return fmt.Errorf("error serializing reaction: %v", err)
+	}
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(path), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	if _, err := c.Do(req, http.StatusCreated); err != nil {
+		// github responds with 200 if the reaction already exists
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusOK {
+			return nil
+		}
+		return fmt.Errorf("error creating reaction %s: %w", content, err)
+	}
+	return nil
+}
+
+func (c *Client) CreateLabel(ctx context.Context, repo string, label *Label) error {
+	payload, err := json.Marshal(label)
+	if err != nil {
+		return fmt.Errorf("error serializing label: %v", err)
+	}
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(fmt.Sprintf("/repos/%s/labels", repo)), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	if _, err := c.Do(req, http.StatusCreated); err != nil {
+		// github responds with a validation error if the label already exists
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+			if ok, _ := LabelExists(ctx, c, repo, label.Name); ok {
+				return nil
+			}
+		}
+		return fmt.Errorf("error creating label %s: %w", label.Name, err)
+	}
+	return nil
+}
+
+func (c *Client) CreateMilestone(ctx context.Context, repo string, milestone *Milestone) error {
+	payload, err := json.Marshal(milestone)
+	if err != nil {
+		return fmt.Errorf("error serializing milestone: %v", err)
+	}
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(fmt.Sprintf("/repos/%s/milestones", repo)), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	resBody, err := c.Do(req, http.StatusCreated)
+	if err != nil {
+		return fmt.Errorf("error creating milestone %s: %w", milestone.Title, err)
+	}
+	response := struct {
+		Number int `json:"number"`
+	}{}
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return fmt.Errorf("error parsing milestone response body: %v", err)
+	}
+	milestone.Number = response.Number
+	return nil
+}
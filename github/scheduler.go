@@ -0,0 +1,206 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// github's documented secondary limits for content-creating requests
+var defaultContentLimits = []struct {
+	n      int
+	period time.Duration
+}{
+	{80, time.Minute},
+	{500, time.Hour},
+}
+
+const (
+	// pacing kicks in when the remaining primary quota drops below this threshold
+	lowQuota = 100
+	// pause applied to a secondary limit response that does not specify when to retry
+	defaultBackoff = time.Minute
+)
+
+// bucket is a token bucket that allows n requests per period
+type bucket struct {
+	capacity float64
+	rate     float64 // tokens per second
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(n int, period time.Duration, now time.Time) *bucket {
+	return &bucket{
+		capacity: float64(n),
+		rate:     float64(n) / period.Seconds(),
+		tokens:   float64(n),
+		last:     now,
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller
+// has to wait before the token becomes available
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	b.tokens -= 1
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Scheduler paces the requests of a Client so that they stay within github's
+// primary (X-RateLimit-* headers) and secondary (content creation) rate limits
+type Scheduler struct {
+	mu sync.Mutex
+	// minimum interval between successive content-creating requests
+	floor time.Duration
+	// time slot of the next content-creating request
+	next    time.Time
+	buckets []*bucket
+	// primary rate limit as reported by the latest response
+	remaining int
+	reset     time.Time
+	// no request is allowed before this time (after hitting a limit)
+	pausedUntil time.Time
+	now         func() time.Time
+	// if set, Wait never blocks (the limits are still tracked)
+	virtual bool
+}
+
+// NewScheduler returns a scheduler that enforces github's rate limits
+// floor is the minimum interval between content-creating requests (can be zero)
+func NewScheduler(floor time.Duration) *Scheduler {
+	s := &Scheduler{floor: floor, remaining: -1, now: time.Now}
+	for _, limit := range defaultContentLimits {
+		s.buckets = append(s.buckets, newBucket(limit.n, limit.period, s.now()))
+	}
+	return s
+}
+
+// NewFloorScheduler returns a scheduler that enforces only floor and the rate limits that the
+// server reports, without github's content creation limits (for other targets, e.g. gitea)
+func NewFloorScheduler(floor time.Duration) *Scheduler {
+	s := NewScheduler(floor)
+	s.buckets = nil
+	return s
+}
+
+// NewVirtualScheduler returns a scheduler that tracks the rate limits without ever waiting
+// It is used for replaying recorded responses, where there is no server to protect
+func NewVirtualScheduler() *Scheduler {
+	s := NewScheduler(0)
+	s.virtual = true
+	return s
+}
+
+// Wait blocks until a request with the specified method can be made or ctx is cancelled
+func (s *Scheduler) Wait(ctx context.Context, method string) error {
+	d := s.reserve(method)
+	if d <= 0 || s.virtual {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve a slot for a request with the specified method and return the time until that slot
+func (s *Scheduler) reserve(method string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	at := now
+	if s.pausedUntil.After(at) {
+		at = s.pausedUntil
+	}
+
+	// spread the remaining primary quota until the reset time
+	if s.remaining >= 0 && s.reset.After(now) {
+		if s.remaining == 0 {
+			at = later(at, s.reset)
+		} else if s.remaining < lowQuota {
+			at = later(at, now.Add(s.reset.Sub(now)/time.Duration(s.remaining)))
+		}
+		if s.remaining > 0 {
+			s.remaining -= 1
+		}
+	}
+
+	if isWrite(method) {
+		for _, b := range s.buckets {
+			at = later(at, now.Add(b.reserve(now)))
+		}
+		at = later(at, s.next)
+		s.next = at.Add(s.floor)
+	}
+
+	return at.Sub(now)
+}
+
+// Observe updates the scheduler with the rate limit information of resp (with body)
+// It returns the time to wait before retrying the request if resp
+// indicates that a rate limit was exceeded
+func (s *Scheduler) Observe(resp *http.Response, body []byte) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource == "" || resource == "core" {
+		if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+			s.remaining = remaining
+		}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			s.reset = time.Unix(reset, 0)
+		}
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	var backoff time.Duration
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		backoff = time.Duration(retryAfter) * time.Second
+	} else if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		backoff = s.reset.Sub(now)
+	} else if resp.StatusCode == http.StatusTooManyRequests || bytes.Contains(body, []byte("secondary rate limit")) {
+		backoff = defaultBackoff
+	} else {
+		// a genuine permission error
+		return 0, false
+	}
+	if backoff < time.Second {
+		backoff = time.Second
+	}
+	s.pausedUntil = later(s.pausedUntil, now.Add(backoff))
+	return backoff, true
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
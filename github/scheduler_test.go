@@ -0,0 +1,76 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScheduler(floor time.Duration) (*Scheduler, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	s := NewScheduler(floor)
+	s.now = func() time.Time { return now }
+	for _, b := range s.buckets {
+		b.last = now
+	}
+	return s, &now
+}
+
+func Test_Scheduler_ContentCreationLimit(t *testing.T) {
+	s, _ := newTestScheduler(0)
+
+	// the first 80 writes go through immediately
+	for i := 0; i < 80; i++ {
+		assert.Equal(t, time.Duration(0), s.reserve(http.MethodPost))
+	}
+	// the 81st has to wait for a token to be refilled
+	assert.Equal(t, 750*time.Millisecond, s.reserve(http.MethodPost))
+	// reads are not subject to the content creation limits
+	assert.Equal(t, time.Duration(0), s.reserve(http.MethodGet))
+}
+
+func Test_Scheduler_Floor(t *testing.T) {
+	s, now := newTestScheduler(2 * time.Second)
+
+	assert.Equal(t, time.Duration(0), s.reserve(http.MethodPost))
+	assert.Equal(t, 2*time.Second, s.reserve(http.MethodPost))
+	*now = now.Add(5 * time.Second)
+	assert.Equal(t, time.Duration(0), s.reserve(http.MethodPatch))
+}
+
+func Test_Scheduler_PrimaryLimit(t *testing.T) {
+	s, now := newTestScheduler(0)
+	reset := now.Add(10 * time.Minute)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "0")
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	_, limited := s.Observe(resp, nil)
+	assert.False(t, limited)
+	assert.Equal(t, 10*time.Minute, s.reserve(http.MethodGet))
+
+	// spread the remaining quota until the reset
+	resp.Header.Set("X-RateLimit-Remaining", "10")
+	s.Observe(resp, nil)
+	assert.Equal(t, time.Minute, s.reserve(http.MethodGet))
+}
+
+func Test_Scheduler_SecondaryLimit(t *testing.T) {
+	s, _ := newTestScheduler(0)
+
+	resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	resp.Header.Set("Retry-After", "30")
+	backoff, limited := s.Observe(resp, nil)
+	assert.True(t, limited)
+	assert.Equal(t, 30*time.Second, backoff)
+	assert.Equal(t, 30*time.Second, s.reserve(http.MethodGet))
+
+	// a genuine permission error is not retried
+	s, _ = newTestScheduler(0)
+	resp = &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	_, limited = s.Observe(resp, []byte(`{"message":"Resource not accessible by integration"}`))
+	assert.False(t, limited)
+}
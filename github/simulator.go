@@ -0,0 +1,298 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Simulator is a Target that performs the changes in memory (for dry runs)
+// The initial state of each repo is read from the base target (if any), so the
+// issues created by the simulator are numbered just like github would number them.
+// Every change is logged.
+type Simulator struct {
+	base Target
+
+	mu     sync.Mutex
+	repos  map[string]*simulatedRepo
+	nextId int64
+	count  int
+}
+
+type simulatedRepo struct {
+	issues map[int]*Issue
+	// comments indexed by issue number (loaded lazily from the base target)
+	comments   map[int][]*Comment
+	labels     map[string]bool
+	milestones int
+	next       int
+}
+
+// NewSimulator returns a simulator on top of base (which can be nil for an empty repo)
+func NewSimulator(base Target) *Simulator {
+	return &Simulator{base: base, repos: map[string]*simulatedRepo{}}
+}
+
+// repo returns the simulated state of repo (must be called with the lock held)
+func (s *Simulator) repo(ctx context.Context, repo string) (*simulatedRepo, error) {
+	if r, ok := s.repos[repo]; ok {
+		return r, nil
+	}
+	r := &simulatedRepo{issues: map[int]*Issue{}, comments: map[int][]*Comment{}, labels: map[string]bool{}, next: 1}
+	if s.base != nil {
+		issues, err := s.base.ListIssues(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			copied := *issue
+			r.issues[issue.Number] = &copied
+			if issue.Number >= r.next {
+				r.next = issue.Number + 1
+			}
+		}
+		// the pull requests share the numbering of the issues but are not listed
+		if client, ok := s.base.(*Client); ok {
+			last, err := LastIssueNumber(ctx, client, repo)
+			if err != nil {
+				return nil, err
+			}
+			if last >= r.next {
+				r.next = last + 1
+			}
+		}
+	}
+	s.repos[repo] = r
+	return r, nil
+}
+
+func (s *Simulator) CreateIssue(ctx context.Context, repo string, issue *Issue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return err
+	}
+	s.count += 1
+	issue.Number = r.next
+	r.next += 1
+	r.issues[issue.Number] = &Issue{Number: issue.Number, Title: issue.Title, Body: issue.Body, State: "open",
+		Labels: issue.Labels, Assignees: issue.Assignees}
+	r.comments[issue.Number] = []*Comment{}
+	log.Printf("[dry-run] %s: created issue #%d: %s", repo, issue.Number, issue.Title)
+	return nil
+}
+
+func (s *Simulator) UpdateIssue(ctx context.Context, repo string, number int, update IssueUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return err
+	}
+	issue, ok := r.issues[number]
+	if !ok {
+		return fmt.Errorf("issue #%d does not exist in %s", number, repo)
+	}
+	s.count += 1
+	if update.Title != "" {
+		issue.Title = update.Title
+	}
+	if update.Body != "" {
+		issue.Body = update.Body
+	}
+	if update.State != "" {
+		issue.State = update.State
+	}
+	if update.Labels != nil {
+		issue.Labels = append([]string{}, *update.Labels...)
+	}
+	log.Printf("[dry-run] %s: updated issue #%d", repo, number)
+	return nil
+}
+
+func (s *Simulator) GetIssue(ctx context.Context, repo string, number int) (*Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	issue, ok := r.issues[number]
+	if !ok {
+		return nil, fmt.Errorf("issue #%d does not exist in %s", number, repo)
+	}
+	copied := *issue
+	return &copied, nil
+}
+
+func (s *Simulator) ListIssues(ctx context.Context, repo string) ([]*Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	issues := []*Issue{}
+	for _, issue := range r.issues {
+		copied := *issue
+		issues = append(issues, &copied)
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Number < issues[j].Number })
+	return issues, nil
+}
+
+// comments returns the comments of issue number (must be called with the lock held)
+func (s *Simulator) comments(ctx context.Context, repo string, number int) (*simulatedRepo, []*Comment, error) {
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := r.issues[number]; !ok {
+		return nil, nil, fmt.Errorf("issue #%d does not exist in %s", number, repo)
+	}
+	comments, ok := r.comments[number]
+	if !ok {
+		comments = []*Comment{}
+		if s.base != nil {
+			if comments, err = s.base.ListComments(ctx, repo, number); err != nil {
+				return nil, nil, err
+			}
+		}
+		r.comments[number] = comments
+	}
+	return r, comments, nil
+}
+
+func (s *Simulator) CreateComment(ctx context.Context, repo string, number int, comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, comments, err := s.comments(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	s.count += 1
+	s.nextId += 1
+	comment.Id = s.nextId
+	r.comments[number] = append(comments, &Comment{Id: comment.Id, Body: comment.Body})
+	log.Printf("[dry-run] %s: added comment %d to issue #%d", repo, comment.Id, number)
+	return nil
+}
+
+func (s *Simulator) EditComment(ctx context.Context, repo string, id int64, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return err
+	}
+	s.count += 1
+	for _, comments := range r.comments {
+		for _, comment := range comments {
+			if comment.Id == id {
+				comment.Body = body
+			}
+		}
+	}
+	log.Printf("[dry-run] %s: edited comment %d", repo, id)
+	return nil
+}
+
+func (s *Simulator) ListComments(ctx context.Context, repo string, number int) ([]*Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, comments, err := s.comments(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	copies := make([]*Comment, len(comments))
+	for idx, comment := range comments {
+		copied := *comment
+		copies[idx] = &copied
+	}
+	return copies, nil
+}
+
+func (s *Simulator) CreateIssueReaction(ctx context.Context, repo string, number int, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += 1
+	log.Printf("[dry-run] %s: added reaction %s to issue #%d", repo, content, number)
+	return nil
+}
+
+func (s *Simulator) CreateCommentReaction(ctx context.Context, repo string, id int64, content string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += 1
+	log.Printf("[dry-run] %s: added reaction %s to comment %d", repo, content, id)
+	return nil
+}
+
+// AddBlockedBy fails with ErrDependenciesUnsupported if the base target does not support
+// dependencies, so that the dry run follows the path of the actual run
+func (s *Simulator) AddBlockedBy(ctx context.Context, repo string, number, blocker int) error {
+	if _, ok := s.base.(Dependencies); s.base != nil && !ok {
+		return ErrDependenciesUnsupported
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += 1
+	log.Printf("[dry-run] %s: marked issue #%d as blocked by #%d", repo, number, blocker)
+	return nil
+}
+
+// AddSubIssue fails with ErrSubIssuesUnsupported if the base target does not support sub-issues
+func (s *Simulator) AddSubIssue(ctx context.Context, repo string, number, child int) error {
+	if _, ok := s.base.(SubIssues); s.base != nil && !ok {
+		return ErrSubIssuesUnsupported
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count += 1
+	log.Printf("[dry-run] %s: added #%d as a sub-issue of #%d", repo, child, number)
+	return nil
+}
+
+func (s *Simulator) CreateLabel(ctx context.Context, repo string, label *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if !r.labels[label.Name] {
+		s.count += 1
+		r.labels[label.Name] = true
+		log.Printf("[dry-run] %s: created label %s", repo, label.Name)
+	}
+	return nil
+}
+
+func (s *Simulator) CreateMilestone(ctx context.Context, repo string, milestone *Milestone) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.repo(ctx, repo)
+	if err != nil {
+		return err
+	}
+	s.count += 1
+	r.milestones += 1
+	milestone.Number = r.milestones
+	log.Printf("[dry-run] %s: created milestone %d: %s", repo, milestone.Number, milestone.Title)
+	return nil
+}
+
+// RequestCount returns the number of requests made to the base target
+// plus the number of simulated changes
+func (s *Simulator) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.count
+	if s.base != nil {
+		count += s.base.RequestCount()
+	}
+	return count
+}
@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Simulator_NumbersFollowTheBaseRepo(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddIssue("o/r", "existing", "")
+	srv.Repo("o/r").Issues[0].Comments = []*githubtest.Comment{{Id: 7, Body: "existing comment"}}
+	client := NewClient("token", false, false, WithEndpoint(srv.URL))
+	sim := NewSimulator(client)
+	ctx := context.Background()
+
+	issue := &Issue{Title: "new", Body: "body", deferredBody: "final body"}
+	assert.Nil(t, issue.Post(ctx, sim, "o/r"))
+	assert.Equal(t, 2, issue.Number)
+	comment := &Comment{Body: "a comment"}
+	assert.Nil(t, comment.Post(ctx, sim, "o/r", 1))
+
+	issues, err := sim.ListIssues(ctx, "o/r")
+	assert.Nil(t, err)
+	if assert.Len(t, issues, 2) {
+		assert.Equal(t, "final body", issues[1].Body)
+	}
+	comments, err := sim.ListComments(ctx, "o/r", 1)
+	assert.Nil(t, err)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, int64(7), comments[0].Id)
+		assert.Equal(t, comment.Id, comments[1].Id)
+	}
+	assert.NotNil(t, (&Comment{Body: "x"}).Post(ctx, sim, "o/r", 5))
+
+	// nothing was changed in the repo
+	assert.Len(t, srv.Repo("o/r").Issues, 1)
+	assert.Len(t, srv.Repo("o/r").Issues[0].Comments, 1)
+}
+
+func Test_Simulator_NumbersSkipPullRequests(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddIssue("o/r", "existing", "")
+	srv.AddPullRequest("o/r", "a pull request")
+	sim := NewSimulator(NewClient("token", false, false, WithEndpoint(srv.URL)))
+
+	issue := &Issue{Title: "new", Body: "body"}
+	assert.Nil(t, issue.Post(context.Background(), sim, "o/r"))
+	assert.Equal(t, 3, issue.Number)
+}
+
+// a target without the optional interfaces
+type basicTarget struct {
+	Target
+}
+
+func Test_Simulator_OptionalInterfaces(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	srv.AddIssue("o/r", "first", "")
+	srv.AddIssue("o/r", "second", "")
+	client := NewClient("token", false, false, WithEndpoint(srv.URL))
+	ctx := context.Background()
+
+	sim := NewSimulator(client)
+	assert.Nil(t, sim.AddBlockedBy(ctx, "o/r", 2, 1))
+	assert.Nil(t, sim.AddSubIssue(ctx, "o/r", 1, 2))
+
+	sim = NewSimulator(basicTarget{client})
+	assert.ErrorIs(t, sim.AddBlockedBy(ctx, "o/r", 2, 1), ErrDependenciesUnsupported)
+	assert.ErrorIs(t, sim.AddSubIssue(ctx, "o/r", 1, 2), ErrSubIssuesUnsupported)
+	assert.Empty(t, srv.Repo("o/r").Issues[1].BlockedBy)
+}
@@ -0,0 +1,82 @@
+package github
+
+import "context"
+
+// Target is the backend that the issues are migrated to
+// Client implements it with the github REST API and Simulator simulates it in memory
+type Target interface {
+	// CreateIssue creates the issue and sets its Number
+	CreateIssue(ctx context.Context, repo string, issue *Issue) error
+	// UpdateIssue changes the non-empty fields of update in the issue
+	UpdateIssue(ctx context.Context, repo string, number int, update IssueUpdate) error
+	GetIssue(ctx context.Context, repo string, number int) (*Issue, error)
+	// ListIssues returns all the issues (but not pull requests) of the repo in ascending order
+	ListIssues(ctx context.Context, repo string) ([]*Issue, error)
+	// CreateComment adds the comment to the issue and sets its Id
+	CreateComment(ctx context.Context, repo string, number int, comment *Comment) error
+	EditComment(ctx context.Context, repo string, id int64, body string) error
+	ListComments(ctx context.Context, repo string, number int) ([]*Comment, error)
+	// CreateIssueReaction and CreateCommentReaction add a reaction (e.g. "+1")
+	// unless the authenticated user has already reacted with it
+	CreateIssueReaction(ctx context.Context, repo string, number int, content string) error
+	CreateCommentReaction(ctx context.Context, repo string, id int64, content string) error
+	// CreateLabel creates the label unless it already exists
+	CreateLabel(ctx context.Context, repo string, label *Label) error
+	// CreateMilestone creates the milestone and sets its Number
+	CreateMilestone(ctx context.Context, repo string, milestone *Milestone) error
+	// RequestCount returns the number of API requests made so far
+	RequestCount() int
+}
+
+// IssueUpdate holds the changes to an issue (empty fields are left unchanged)
+type IssueUpdate struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+	// "open" or "closed"
+	State string `json:"state,omitempty"`
+	// the complete set of labels (nil leaves the labels unchanged)
+	Labels *[]string `json:"labels,omitempty"`
+}
+
+// SetClosed adds the changes that close (or reopen) the live issue to the update,
+// keeping ClosedLabel in step with the state (imported issues are marked closed with it)
+// It returns false if the issue is already in that state.
+func (u *IssueUpdate) SetClosed(live *Issue, closed bool) bool {
+	labelled := false
+	for _, label := range live.Labels {
+		labelled = labelled || label == ClosedLabel
+	}
+	if closed == (live.State == "closed" || labelled) {
+		return false
+	}
+	if closed {
+		u.State = "closed"
+		labels := append(append([]string{}, live.Labels...), ClosedLabel)
+		u.Labels = &labels
+		return true
+	}
+	u.State = "open"
+	if labelled {
+		labels := []string{}
+		for _, label := range live.Labels {
+			if label != ClosedLabel {
+				labels = append(labels, label)
+			}
+		}
+		u.Labels = &labels
+	}
+	return true
+}
+
+type Label struct {
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Milestone struct {
+	Number      int    `json:"number,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state,omitempty"`
+}
@@ -0,0 +1,62 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrSubIssuesUnsupported is returned by AddSubIssue when the server does not support sub-issues
+// (e.g. older versions of github enterprise)
+var ErrSubIssuesUnsupported = errors.New("sub-issues are not supported")
+
+// Task is a child task of an issue
+type Task struct {
+	Number int
+	Closed bool
+}
+
+// SubIssues is implemented by the targets that support sub-issues
+type SubIssues interface {
+	// AddSubIssue adds the issue child as a sub-issue of the issue number
+	// (it is not an error if it is already a sub-issue)
+	AddSubIssue(ctx context.Context, repo string, number, child int) error
+}
+
+func (c *Client) AddSubIssue(ctx context.Context, repo string, number, child int) error {
+	// sub-issues refer to the (database) id of the child issue
+	issue, err := c.GetIssue(ctx, repo, child)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("%s/%d/sub_issues", (&Issue{}).Path(repo), number)
+	// github responds with the same validation error to existing sub-issues and to invalid
+	// ones (e.g. of another parent or over the limit), so the existing ones are looked up first
+	ids, err := c.issueIds(ctx, path)
+	if isNotFound(err) {
+		return fmt.Errorf("error listing the sub-issues of #%d: %w", number, ErrSubIssuesUnsupported)
+	}
+	if err != nil {
+		return fmt.Errorf("error listing the sub-issues of #%d: %w", number, err)
+	}
+	for _, id := range ids {
+		if id == issue.Id {
+			return nil
+		}
+	}
+
+	payload, err := json.Marshal(map[string]int64{"sub_issue_id": issue.Id})
+	if err != nil {
+		return fmt.Errorf("error serializing sub-issue: %v", err)
+	}
+	req, err := c.NewRequest(ctx, http.MethodPost, c.URL(path), payload)
+	if err != nil {
+		return fmt.Errorf("failed to prepare request: %w", err)
+	}
+	if _, err := c.Do(req, http.StatusCreated); err != nil {
+		return fmt.Errorf("error adding #%d as a sub-issue of #%d: %w", child, number, err)
+	}
+	return nil
+}
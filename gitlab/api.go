@@ -0,0 +1,463 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	apiTimeout = 10 * time.Second
+	// page size for list requests (gitlab's maximum)
+	apiPerPage = 100
+	// number of times a rate-limited request is retried
+	apiRetries = 3
+	// wait before retrying a rate-limited request without a Retry-After header
+	apiRetryAfter = 10 * time.Second
+)
+
+// StatusError is returned by the APIClient when the response status code
+// differs from the expected one
+type StatusError struct {
+	StatusCode int
+	Expected   int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("gitlab: status code: %d (expected %d)", e.StatusCode, e.Expected)
+}
+
+// IsAuth returns true if the request was rejected due to invalid credentials or insufficient permissions
+func (e *StatusError) IsAuth() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// APIClient reads issues from the gitlab REST API (v4) as an alternative to an export
+type APIClient struct {
+	endpoint string
+	// the URL of the GraphQL API (for what the REST API does not expose)
+	graphql string
+	token   string
+	client  *http.Client
+	debug   bool
+	count   atomic.Int64
+
+	// minimum delay between successive write requests
+	delay     time.Duration
+	mu        sync.Mutex
+	lastWrite time.Time
+}
+
+type APIOption func(*APIClient)
+
+// WithWriteDelay paces the requests that create or change content
+func WithWriteDelay(delay time.Duration) APIOption {
+	return func(c *APIClient) {
+		c.delay = delay
+	}
+}
+
+// NewAPIClient returns a client for the gitlab instance at baseURL (e.g. https://gitlab.com)
+func NewAPIClient(baseURL, token string, debug bool, opts ...APIOption) *APIClient {
+	c := &APIClient{
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/api/v4",
+		graphql:  strings.TrimSuffix(baseURL, "/") + "/api/graphql",
+		token:    token,
+		client:   &http.Client{Timeout: apiTimeout},
+		debug:    debug,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *APIClient) RequestCount() int {
+	return int(c.count.Load())
+}
+
+// projectPath returns the API path of the project (a numeric ID or a path like group/project)
+func projectPath(project string) string {
+	return "/projects/" + url.PathEscape(project)
+}
+
+// pace waits until the write delay has passed since the previous write request
+func (c *APIClient) pace(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wait := time.Until(c.lastWrite.Add(c.delay)); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastWrite = time.Now()
+	return nil
+}
+
+// do sends the request and decodes the response into result (if not nil)
+// Requests that are rejected due to rate limiting are retried after the period
+// that gitlab asks for. It returns the response headers.
+func (c *APIClient) do(ctx context.Context, method, path string, payload interface{}, expected int, result interface{}) (http.Header, error) {
+	return c.doURL(ctx, method, c.endpoint+path, payload, expected, result)
+}
+
+// doURL is do for an absolute URL
+func (c *APIClient) doURL(ctx context.Context, method, url string, payload interface{}, expected int, result interface{}) (http.Header, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("failed to serialize request: %v", err)
+		}
+	}
+	if method != http.MethodGet {
+		if err := c.pace(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		header, err := c.send(ctx, method, url, body, expected, result)
+		var serr *StatusError
+		if attempt == apiRetries || !errors.As(err, &serr) || serr.StatusCode != http.StatusTooManyRequests {
+			return header, err
+		}
+		wait := apiRetryAfter
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		log.Printf("[gitlab] rate limited: retrying in %s", wait)
+		select {
+		case <-ctx.Done():
+			return header, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *APIClient) send(ctx context.Context, method, url string, body []byte, expected int, result interface{}) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the http request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gl2gh")
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+	if c.debug {
+		log.Printf("[gitlab] %s %s\n%s", method, req.URL, string(body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: request error: %w", err)
+	}
+	defer resp.Body.Close()
+	c.count.Add(1)
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: failed to read response body: %v", err)
+	}
+	if c.debug {
+		log.Printf("[gitlab] STATUS %d", resp.StatusCode)
+	}
+	if resp.StatusCode != expected {
+		return resp.Header, &StatusError{StatusCode: resp.StatusCode, Expected: expected, Body: respBody}
+	}
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return resp.Header, fmt.Errorf("gitlab: error parsing response body: %v", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// list fetches all the pages of path (following the X-Next-Page header)
+// and calls add with the raw items of each page
+func (c *APIClient) list(ctx context.Context, path string, add func(page json.RawMessage) error) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for page := "1"; page != ""; {
+		var items json.RawMessage
+		header, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s%sper_page=%d&page=%s", path, sep, apiPerPage, page), nil, http.StatusOK, &items)
+		if err != nil {
+			return err
+		}
+		if err := add(items); err != nil {
+			return fmt.Errorf("gitlab: error parsing response body: %v", err)
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return nil
+}
+
+// apiIssue is an issue as returned by the API
+type apiIssue struct {
+	Iid         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Author      struct {
+		Id int `json:"id"`
+	} `json:"author"`
+	Assignees []struct {
+		Id int `json:"id"`
+	} `json:"assignees"`
+	State     string     `json:"state"`
+	IssueType string     `json:"issue_type"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	// the discussion of the issue has been locked
+	DiscussionLocked bool `json:"discussion_locked"`
+}
+
+// convert the issue to the model of the export
+func (i *apiIssue) convert() *Issue {
+	issue := &Issue{
+		Id:               i.Iid,
+		Title:            i.Title,
+		Description:      i.Description,
+		AuthorId:         i.Author.Id,
+		State:            i.State,
+		Type:             i.IssueType,
+		CreatedAt:        i.CreatedAt,
+		UpdatedAt:        i.UpdatedAt,
+		Comments:         []*Comment{},
+		DiscussionLocked: i.DiscussionLocked,
+	}
+	if i.ClosedAt != nil {
+		issue.ClosedAt = *i.ClosedAt
+	}
+	for _, assignee := range i.Assignees {
+		issue.Assignees = append(issue.Assignees, struct {
+			UserId int `json:"user_id"`
+		}{assignee.Id})
+	}
+	return issue
+}
+
+type apiNote struct {
+	Id     int    `json:"id"`
+	Body   string `json:"body"`
+	Author struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (n *apiNote) convert() *Comment {
+	comment := &Comment{Id: n.Id, Note: n.Body, AuthorId: n.Author.Id, CreatedAt: n.CreatedAt}
+	comment.Author.Name = n.Author.Name
+	return comment
+}
+
+// Issues fetches the issues of the project with their notes and returns them
+// exactly like Parse does for an export (ordered by ID with the comments filtered)
+func (c *APIClient) Issues(ctx context.Context, project string, commentExclusionFilter []string) ([]*Issue, error) {
+	issues := []*Issue{}
+	path := projectPath(project) + "/issues?scope=all&order_by=created_at&sort=asc"
+	err := c.list(ctx, path, func(page json.RawMessage) error {
+		batch := []*apiIssue{}
+		if err := json.Unmarshal(page, &batch); err != nil {
+			return err
+		}
+		for _, i := range batch {
+			issues = append(issues, i.convert())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the issues of %s: %w", project, err)
+	}
+	// the links and the parents are resolved by the path of the project
+	fullPath, err := c.FullPath(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		if issue.Comments, err = c.Notes(ctx, project, issue.Id); err != nil {
+			return nil, err
+		}
+		if issue.Links, err = c.Links(ctx, fullPath, issue.Id); err != nil {
+			return nil, err
+		}
+		if issue.Type == TypeTask {
+			if issue.ParentId, err = c.Parent(ctx, fullPath, issue.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return curateIssues(issues, commentExclusionFilter), nil
+}
+
+// Issue fetches the issue iid (without its notes)
+func (c *APIClient) Issue(ctx context.Context, project string, iid int) (*Issue, error) {
+	issue := &apiIssue{}
+	path := fmt.Sprintf("%s/issues/%d", projectPath(project), iid)
+	if _, err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, issue); err != nil {
+		return nil, fmt.Errorf("failed to fetch issue #%d: %w", iid, err)
+	}
+	return issue.convert(), nil
+}
+
+// FullPath returns the path of the project (e.g. group/project) if project is a numeric ID
+func (c *APIClient) FullPath(ctx context.Context, project string) (string, error) {
+	if _, err := strconv.Atoi(project); err != nil {
+		return project, nil
+	}
+	response := struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	}{}
+	if _, err := c.do(ctx, http.MethodGet, projectPath(project), nil, http.StatusOK, &response); err != nil {
+		return "", fmt.Errorf("failed to fetch project %s: %w", project, err)
+	}
+	return response.PathWithNamespace, nil
+}
+
+// Notes fetches the notes of the issue iid (including system notes, as in an export)
+func (c *APIClient) Notes(ctx context.Context, project string, iid int) ([]*Comment, error) {
+	comments := []*Comment{}
+	path := fmt.Sprintf("%s/issues/%d/notes?order_by=created_at&sort=asc", projectPath(project), iid)
+	err := c.list(ctx, path, func(page json.RawMessage) error {
+		batch := []*apiNote{}
+		if err := json.Unmarshal(page, &batch); err != nil {
+			return err
+		}
+		for _, n := range batch {
+			comments = append(comments, n.convert())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the notes of issue #%d: %w", iid, err)
+	}
+	return comments, nil
+}
+
+type apiLink struct {
+	Iid        int    `json:"iid"`
+	LinkType   string `json:"link_type"`
+	References struct {
+		// e.g. group/project#4
+		Full string `json:"full"`
+	} `json:"references"`
+}
+
+// Links fetches the issues that are linked to the issue iid
+// The project of a linked issue is recorded only if it differs from project (which must be a path).
+func (c *APIClient) Links(ctx context.Context, project string, iid int) ([]*IssueLink, error) {
+	batch := []*apiLink{}
+	path := fmt.Sprintf("%s/issues/%d/links", projectPath(project), iid)
+	if _, err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &batch); err != nil {
+		return nil, fmt.Errorf("failed to list the links of issue #%d: %w", iid, err)
+	}
+	links := []*IssueLink{}
+	for _, l := range batch {
+		link := &IssueLink{Iid: l.Iid, LinkType: l.LinkType}
+		if path, _, ok := strings.Cut(l.References.Full, "#"); ok && path != project {
+			link.Project = path
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// the parent of a work item is exposed only by the GraphQL API
+const parentQuery = `query($path: ID!, $iid: String!) {
+  project(fullPath: $path) {
+    workItems(iid: $iid) {
+      nodes { widgets { ... on WorkItemWidgetHierarchy { parent { iid } } } }
+    }
+  }
+}`
+
+// Parent fetches the iid of the parent of the work item iid (zero if it has none)
+// project must be a path, as GraphQL does not address projects by ID
+func (c *APIClient) Parent(ctx context.Context, project string, iid int) (int, error) {
+	payload := map[string]interface{}{
+		"query":     parentQuery,
+		"variables": map[string]string{"path": project, "iid": strconv.Itoa(iid)},
+	}
+	response := struct {
+		Data struct {
+			Project *struct {
+				WorkItems struct {
+					Nodes []struct {
+						Widgets []struct {
+							Parent *struct {
+								Iid string `json:"iid"`
+							} `json:"parent"`
+						} `json:"widgets"`
+					} `json:"nodes"`
+				} `json:"workItems"`
+			} `json:"project"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	// gitlab responds to queries with 200 (even if they fail)
+	if _, err := c.doURL(ctx, http.MethodPost, c.graphql, payload, http.StatusOK, &response); err != nil {
+		return 0, fmt.Errorf("failed to fetch the parent of issue #%d: %w", iid, err)
+	}
+	if len(response.Errors) > 0 {
+		return 0, fmt.Errorf("failed to fetch the parent of issue #%d: %s", iid, response.Errors[0].Message)
+	}
+	if response.Data.Project == nil {
+		return 0, fmt.Errorf("failed to fetch the parent of issue #%d: project %s not found", iid, project)
+	}
+	for _, node := range response.Data.Project.WorkItems.Nodes {
+		for _, widget := range node.Widgets {
+			if widget.Parent != nil {
+				parent, err := strconv.Atoi(widget.Parent.Iid)
+				if err != nil {
+					return 0, fmt.Errorf("failed to parse the parent of issue #%d: %v", iid, err)
+				}
+				return parent, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// CreateNote adds a note to the issue iid and returns it
+func (c *APIClient) CreateNote(ctx context.Context, project string, iid int, body string) (*Comment, error) {
+	note := &apiNote{}
+	path := fmt.Sprintf("%s/issues/%d/notes", projectPath(project), iid)
+	if _, err := c.do(ctx, http.MethodPost, path, map[string]string{"body": body}, http.StatusCreated, note); err != nil {
+		return nil, fmt.Errorf("failed to add a note to issue #%d: %w", iid, err)
+	}
+	return note.convert(), nil
+}
+
+// IssueEdit is the set of changes to an issue (empty fields are left unchanged)
+type IssueEdit struct {
+	// "close" or "reopen"
+	StateEvent       string `json:"state_event,omitempty"`
+	DiscussionLocked *bool  `json:"discussion_locked,omitempty"`
+}
+
+// EditIssue applies the changes to the issue iid
+func (c *APIClient) EditIssue(ctx context.Context, project string, iid int, edit IssueEdit) error {
+	path := fmt.Sprintf("%s/issues/%d", projectPath(project), iid)
+	if _, err := c.do(ctx, http.MethodPut, path, edit, http.StatusOK, nil); err != nil {
+		return fmt.Errorf("failed to edit issue #%d: %w", iid, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/gitlab/gitlabtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_APIClient_Issues(t *testing.T) {
+	srv := gitlabtest.NewServer()
+	defer srv.Close()
+	for i := 1; i <= 120; i++ {
+		srv.AddIssue("group/project", fmt.Sprintf("issue %d", i), "description")
+	}
+	first := srv.Project("group/project").Issues[0]
+	first.AuthorId, first.Assignees = 7, []int{8}
+	first.State, first.ClosedAt = "closed", time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 105; i++ {
+		srv.AddNote(first, fmt.Sprintf("note %d", i))
+	}
+	srv.AddNote(first, "assigned to @someone")
+	srv.Project("group/project").Issues[2].ParentIid = 1
+
+	client := NewAPIClient(srv.URL, "token", false)
+	issues, err := client.Issues(context.Background(), "group/project", []string{"assigned to"})
+	assert.Nil(t, err)
+	if assert.Len(t, issues, 120) {
+		assert.Equal(t, 1, issues[0].Id)
+		assert.Equal(t, 7, issues[0].AuthorId)
+		assert.Equal(t, 8, issues[0].Assignees[0].UserId)
+		assert.True(t, issues[0].IsClosed())
+		assert.False(t, issues[1].IsClosed())
+		assert.Len(t, issues[0].Comments, 105)
+		assert.Equal(t, "note 0", issues[0].Comments[0].Note)
+		assert.Equal(t, first.Notes[0].Id, issues[0].Comments[0].Id)
+		assert.Equal(t, 120, issues[119].Id)
+		assert.False(t, issues[1].IsTask())
+		assert.True(t, issues[2].IsTask())
+		assert.Equal(t, 1, issues[2].ParentId)
+	}
+
+	// projects can also be addressed by ID
+	srv.Project("group/project").Issues[1].Links = []*gitlabtest.Link{{Iid: 1, LinkType: "relates_to"}}
+	issues, err = client.Issues(context.Background(), strconv.Itoa(srv.Project("group/project").Id), nil)
+	assert.Nil(t, err)
+	if assert.Len(t, issues, 120) {
+		if assert.Len(t, issues[1].Links, 1) {
+			assert.Equal(t, "", issues[1].Links[0].Project)
+		}
+		assert.Equal(t, 1, issues[2].ParentId)
+	}
+
+	_, err = NewAPIClient(srv.URL, "", false).Issues(context.Background(), "group/project", nil)
+	var statusErr *StatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.True(t, statusErr.IsAuth())
+	}
+}
+
+func Test_APIClient_Writes(t *testing.T) {
+	srv := gitlabtest.NewServer()
+	defer srv.Close()
+	issue := srv.AddIssue("group/project", "an issue", "")
+	client := NewAPIClient(srv.URL, "token", false, WithWriteDelay(time.Millisecond))
+	ctx := context.Background()
+
+	// rate-limited requests are retried
+	srv.Throttle(2)
+	note, err := client.CreateNote(ctx, "group/project", 1, "a note")
+	assert.Nil(t, err)
+	if assert.Len(t, issue.Notes, 1) {
+		assert.Equal(t, issue.Notes[0].Id, note.Id)
+		assert.Equal(t, "a note", issue.Notes[0].Body)
+	}
+
+	locked := true
+	assert.Nil(t, client.EditIssue(ctx, "group/project", 1, IssueEdit{StateEvent: "close", DiscussionLocked: &locked}))
+	assert.Equal(t, "closed", issue.State)
+	assert.True(t, issue.DiscussionLocked)
+
+	var serr *StatusError
+	_, err = client.CreateNote(ctx, "group/project", 2, "a note")
+	if assert.True(t, errors.As(err, &serr)) {
+		assert.Equal(t, 404, serr.StatusCode)
+	}
+}
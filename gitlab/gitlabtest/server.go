@@ -0,0 +1,405 @@
+// Package gitlabtest provides an in-process fake of the gitlab REST API (v4)
+// that keeps state, for testing the API source offline
+// It also answers the GraphQL query for the parents of tasks.
+package gitlabtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Note struct {
+	Id         int
+	Body       string
+	AuthorId   int
+	AuthorName string
+	CreatedAt  time.Time
+	System     bool
+}
+
+type Issue struct {
+	Iid         int
+	Title       string
+	Description string
+	AuthorId    int
+	Assignees   []int
+	State       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ClosedAt    time.Time
+	Notes       []*Note
+	// DiscussionLocked is set when the discussion of the issue has been locked
+	DiscussionLocked bool
+	Links            []*Link
+	// ParentIid makes the issue a child task of the issue with this iid
+	ParentIid int
+}
+
+// Link is a link to another issue
+type Link struct {
+	Iid      int
+	LinkType string
+	// the path of the project of the linked issue (empty for the same project)
+	Project string
+}
+
+// Project is the state of a fake gitlab project
+type Project struct {
+	// Id is the numeric ID that the project can be addressed by instead of its path
+	Id     int
+	Issues []*Issue
+}
+
+// Server is a fake gitlab API (served under /api/v4)
+// Projects are created on demand the first time they are accessed
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	projects map[string]*Project
+	nextId   int
+	requests int
+	// number of upcoming requests that are rejected as rate-limited
+	throttled int
+}
+
+func NewServer() *Server {
+	s := &Server{projects: map[string]*Project{}, nextId: 1000}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Project returns the state of the project with the specified path (e.g. "group/project")
+func (s *Server) Project(path string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.project(path)
+}
+
+// AddIssue adds an open issue with the next iid to the project and returns it
+func (s *Server) AddIssue(project, title, description string) *Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.project(project)
+	createdAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(len(p.Issues)) * time.Hour)
+	issue := &Issue{Iid: len(p.Issues) + 1, Title: title, Description: description, State: "opened",
+		CreatedAt: createdAt, UpdatedAt: createdAt}
+	p.Issues = append(p.Issues, issue)
+	return issue
+}
+
+// AddNote adds a note to the issue (which is marked as updated) and returns it
+func (s *Server) AddNote(issue *Issue, body string) *Note {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextId++
+	note := &Note{Id: s.nextId, Body: body, CreatedAt: issue.UpdatedAt.Add(time.Minute)}
+	issue.Notes = append(issue.Notes, note)
+	issue.UpdatedAt = note.CreatedAt
+	return note
+}
+
+// Update applies change to the issue and marks it as updated
+func (s *Server) Update(issue *Issue, change func(issue *Issue)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change(issue)
+	issue.UpdatedAt = issue.UpdatedAt.Add(time.Minute)
+}
+
+// Throttle makes the server reject the next n requests as rate-limited (with Retry-After: 0)
+func (s *Server) Throttle(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttled = n
+}
+
+// Requests returns the number of requests served so far
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) project(path string) *Project {
+	p, ok := s.projects[path]
+	if !ok {
+		p = &Project{Id: len(s.projects) + 1}
+		s.projects[path] = p
+	}
+	return p
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	if s.throttled > 0 {
+		s.throttled--
+		w.Header().Set("Retry-After", "0")
+		respond(w, http.StatusTooManyRequests, map[string]string{"message": "Retry later"})
+		return
+	}
+	if r.Header.Get("PRIVATE-TOKEN") == "" {
+		respond(w, http.StatusUnauthorized, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+	if r.URL.Path == "/api/graphql" && r.Method == http.MethodPost {
+		s.graphql(w, r)
+		return
+	}
+	// the project path is URL-encoded in a single segment
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4"), "/"), "/")
+	if len(parts) < 2 || parts[0] != "projects" {
+		respond(w, http.StatusNotFound, map[string]string{"message": "404 Not Found"})
+		return
+	}
+	path, err := url.PathUnescape(parts[1])
+	if err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	for name, p := range s.projects {
+		if strconv.Itoa(p.Id) == path {
+			path = name
+		}
+	}
+	s.route(w, r, path, s.project(path), parts[2:])
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request, name string, project *Project, parts []string) {
+	notFound := func() { respond(w, http.StatusNotFound, map[string]string{"message": "404 Not Found"}) }
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		respond(w, http.StatusOK, map[string]interface{}{"id": project.Id, "path_with_namespace": name})
+
+	case len(parts) == 1 && parts[0] == "issues" && r.Method == http.MethodGet:
+		items := []interface{}{}
+		for _, issue := range project.Issues {
+			items = append(items, issueJSON(issue))
+		}
+		respondPage(w, r, items)
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "notes" && r.Method == http.MethodGet:
+		issue := findIssue(project, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		items := []interface{}{}
+		for _, note := range issue.Notes {
+			items = append(items, noteJSON(note))
+		}
+		respondPage(w, r, items)
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "links" && r.Method == http.MethodGet:
+		issue := findIssue(project, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		// the links are not paginated
+		items := []interface{}{}
+		for _, link := range issue.Links {
+			if link.Project != "" {
+				// the issues of other projects are not looked up
+				items = append(items, map[string]interface{}{"iid": link.Iid, "link_type": link.LinkType,
+					"references": map[string]string{"full": fmt.Sprintf("%s#%d", link.Project, link.Iid)}})
+				continue
+			}
+			if linked := findIssue(project, strconv.Itoa(link.Iid)); linked != nil {
+				item := issueJSON(linked)
+				item["link_type"] = link.LinkType
+				item["references"] = map[string]string{"full": fmt.Sprintf("%s#%d", name, linked.Iid)}
+				items = append(items, item)
+			}
+		}
+		respond(w, http.StatusOK, items)
+
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "notes" && r.Method == http.MethodPost:
+		issue := findIssue(project, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		var payload struct {
+			Body string `json:"body"`
+		}
+		if !decode(w, r, &payload) {
+			return
+		}
+		s.nextId++
+		note := &Note{Id: s.nextId, Body: payload.Body, CreatedAt: time.Now()}
+		issue.Notes = append(issue.Notes, note)
+		issue.UpdatedAt = note.CreatedAt
+		respond(w, http.StatusCreated, noteJSON(note))
+
+	case len(parts) == 2 && parts[0] == "issues" && r.Method == http.MethodGet:
+		issue := findIssue(project, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		respond(w, http.StatusOK, issueJSON(issue))
+
+	case len(parts) == 2 && parts[0] == "issues" && r.Method == http.MethodPut:
+		issue := findIssue(project, parts[1])
+		if issue == nil {
+			notFound()
+			return
+		}
+		var payload struct {
+			StateEvent       string `json:"state_event"`
+			DiscussionLocked *bool  `json:"discussion_locked"`
+		}
+		if !decode(w, r, &payload) {
+			return
+		}
+		switch payload.StateEvent {
+		case "close":
+			if issue.State != "closed" {
+				issue.State, issue.ClosedAt = "closed", time.Now()
+			}
+		case "reopen":
+			issue.State, issue.ClosedAt = "opened", time.Time{}
+		}
+		if payload.DiscussionLocked != nil {
+			issue.DiscussionLocked = *payload.DiscussionLocked
+		}
+		issue.UpdatedAt = time.Now()
+		respond(w, http.StatusOK, issueJSON(issue))
+
+	default:
+		notFound()
+	}
+}
+
+// graphql answers the query for the parent of a work item (the only one that the client makes)
+func (s *Server) graphql(w http.ResponseWriter, r *http.Request) {
+	payload := struct {
+		Variables struct {
+			Path string `json:"path"`
+			Iid  string `json:"iid"`
+		} `json:"variables"`
+	}{}
+	if !decode(w, r, &payload) {
+		return
+	}
+	project, ok := s.projects[payload.Variables.Path]
+	if !ok {
+		respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"project": nil}})
+		return
+	}
+	nodes := []interface{}{}
+	if issue := findIssue(project, payload.Variables.Iid); issue != nil {
+		var parent interface{}
+		if issue.ParentIid > 0 {
+			parent = map[string]string{"iid": strconv.Itoa(issue.ParentIid)}
+		}
+		// the widgets of the other types are empty objects
+		nodes = append(nodes, map[string]interface{}{"widgets": []interface{}{map[string]interface{}{}, map[string]interface{}{"parent": parent}}})
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+		"project": map[string]interface{}{"workItems": map[string]interface{}{"nodes": nodes}}}})
+}
+
+func issueJSON(issue *Issue) map[string]interface{} {
+	assignees := []map[string]int{}
+	for _, id := range issue.Assignees {
+		assignees = append(assignees, map[string]int{"id": id})
+	}
+	var closedAt interface{}
+	if !issue.ClosedAt.IsZero() {
+		closedAt = issue.ClosedAt
+	}
+	issueType := "issue"
+	if issue.ParentIid > 0 {
+		issueType = "task"
+	}
+	return map[string]interface{}{
+		"iid":         issue.Iid,
+		"issue_type":  issueType,
+		"title":       issue.Title,
+		"description": issue.Description,
+		"author":      map[string]int{"id": issue.AuthorId},
+		"assignees":   assignees,
+		"state":       issue.State,
+		"created_at":  issue.CreatedAt,
+		"updated_at":  issue.UpdatedAt,
+		"closed_at":   closedAt,
+
+		"discussion_locked": issue.DiscussionLocked,
+	}
+}
+
+func noteJSON(note *Note) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         note.Id,
+		"body":       note.Body,
+		"author":     map[string]interface{}{"id": note.AuthorId, "name": note.AuthorName},
+		"created_at": note.CreatedAt,
+		"system":     note.System,
+	}
+}
+
+func findIssue(project *Project, iid string) *Issue {
+	n, err := strconv.Atoi(iid)
+	if err != nil {
+		return nil
+	}
+	for _, issue := range project.Issues {
+		if issue.Iid == n {
+			return issue
+		}
+	}
+	return nil
+}
+
+// respondPage responds with the page of items requested with the page and per_page
+// query parameters and sets the pagination headers
+func respondPage(w http.ResponseWriter, r *http.Request, items []interface{}) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 20
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	end := start + perPage
+	if end >= len(items) {
+		end = len(items)
+	} else {
+		w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+	}
+	w.Header().Set("X-Page", strconv.Itoa(page))
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	respond(w, http.StatusOK, items[start:end])
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		respond(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
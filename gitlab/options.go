@@ -0,0 +1,113 @@
+package gitlab
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Attribution determines how a mapped github user is referenced
+// in the rendered body of an issue
+type Attribution string
+
+const (
+	// @ghname (notifies the user)
+	AttributionMention Attribution = "mention"
+	// `@ghname` (does not notify the user)
+	AttributionCode Attribution = "code"
+	// [ghname](https://github.com/ghname) (does not notify the user)
+	AttributionLink Attribution = "link"
+	// rendered as code initially and turned into a mention by a follow-up edit
+	// (github does not send notifications for mentions that are added by edits)
+	AttributionDeferred Attribution = "deferred"
+)
+
+var Attributions = []Attribution{AttributionMention, AttributionCode, AttributionLink, AttributionDeferred}
+
+func ParseAttribution(s string) (Attribution, error) {
+	for _, a := range Attributions {
+		if string(a) == s {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown attribution mode %q (one of: %v)", s, Attributions)
+}
+
+// Format returns the reference to the github user ghname
+func (a Attribution) Format(ghname string) string {
+	switch a {
+	case AttributionCode, AttributionDeferred:
+		return fmt.Sprintf("`@%s`", ghname)
+	case AttributionLink:
+		return fmt.Sprintf("[%s](https://github.com/%s)", ghname, ghname)
+	default:
+		return "@" + ghname
+	}
+}
+
+// Options controls the conversion of gitlab issues and comments to markdown
+type Options struct {
+	// the path of the gitlab project (e.g. group/project)
+	Project string
+	// the web URL of the gitlab instance (e.g. https://gitlab.com)
+	// together with Project, it adds links to the original issues and notes
+	GitlabURL string
+	// github user names indexed by gitlab UID
+	Mappings map[int]string
+	// pairs of regex replacement patterns applied to descriptions and notes
+	ReplacePatterns map[string]string
+	// how mapped authors are referenced
+	Attribution Attribution
+	// neutralise @mentions and #123 autolinks in descriptions and notes
+	Sanitize bool
+}
+
+// IssueURL returns the web URL of the original gitlab issue (ok is false if the URL of gitlab is unknown)
+func (opts Options) IssueURL(iid int) (string, bool) {
+	if opts.GitlabURL == "" || opts.Project == "" {
+		return "", false
+	}
+	return fmt.Sprintf("%s/%s/-/issues/%d", strings.TrimSuffix(opts.GitlabURL, "/"), strings.Trim(opts.Project, "/"), iid), true
+}
+
+func (opts Options) render(src string) (string, error) {
+	out, err := ReplaceAll(src, opts.ReplacePatterns)
+	if err != nil {
+		return "", err
+	}
+	if opts.Sanitize {
+		out = Sanitize(out)
+	}
+	return out, nil
+}
+
+var (
+	mentionRe  = regexp.MustCompile("(^|[^\\w`/.@-])(@[A-Za-z0-9][A-Za-z0-9-]*(?:/[A-Za-z0-9_.-]+)?)")
+	autolinkRe = regexp.MustCompile("(^|[^\\w`&/#])(#\\d+)\\b")
+)
+
+// Sanitize wraps @mentions and #123 references in code spans so that github
+// does not notify the mentioned users or link the text to unrelated issues.
+// Fenced code blocks and inline code spans are left untouched.
+func Sanitize(src string) string {
+	lines := strings.Split(src, "\n")
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		// odd-numbered segments are within inline code spans
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = mentionRe.ReplaceAllString(segments[j], "$1`$2`")
+			segments[j] = autolinkRe.ReplaceAllString(segments[j], "$1`$2`")
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+	return strings.Join(lines, "\n")
+}
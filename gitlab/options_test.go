@@ -0,0 +1,44 @@
+package gitlab
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Attribution_Format(t *testing.T) {
+	kases := []struct {
+		attribution Attribution
+		expected    string
+	}{
+		{AttributionMention, "@kkentzo"},
+		{AttributionCode, "`@kkentzo`"},
+		{AttributionLink, "[kkentzo](https://github.com/kkentzo)"},
+		{AttributionDeferred, "`@kkentzo`"},
+	}
+
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, kase.attribution.Format("kkentzo"))
+	}
+
+	_, err := ParseAttribution("shout")
+	assert.NotNil(t, err)
+}
+
+func Test_Sanitize(t *testing.T) {
+	kases := []struct {
+		src      string
+		expected string
+	}{
+		{"ping @kkentzo about #12", "ping `@kkentzo` about `#12`"},
+		{"@org/team please check", "`@org/team` please check"},
+		{"mail me at foo@example.com", "mail me at foo@example.com"},
+		{"already `@quoted` and `#3`", "already `@quoted` and `#3`"},
+		{"see http://foo.com/page#12 or &#123;", "see http://foo.com/page#12 or &#123;"},
+		{"```\n@kkentzo #1\n```\n@kkentzo", "```\n@kkentzo #1\n```\n`@kkentzo`"},
+	}
+
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, Sanitize(kase.src))
+	}
+}
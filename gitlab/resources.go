@@ -21,16 +21,85 @@ type Issue struct {
 	Comments  []*Comment `json:"notes"`
 	State     string     `json:"state"`
 	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 	ClosedAt  time.Time  `json:"closed_at"`
+	// the discussion of the issue has been locked
+	DiscussionLocked bool `json:"discussion_locked"`
+	// only the export includes the award emoji
+	AwardEmoji []*AwardEmoji `json:"award_emoji"`
+	// The linked issues ("linked items") and the parent of a task are set only by the API source
+	// (APIClient.Issues), as gitlab's export does not include the links between issues.
+	Links []*IssueLink `json:"-"`
+	// the type of the work item (issue, incident or task)
+	Type string `json:"issue_type"`
+	// the iid of the parent issue of a task
+	ParentId int `json:"-"`
 }
 
-func (issue Issue) Convert(mappings map[int]string, replPatterns map[string]string) (string, error) {
+// the work item type of child tasks
+const TypeTask = "task"
+
+// the types of issue links
+const (
+	LinkRelatesTo   = "relates_to"
+	LinkBlocks      = "blocks"
+	LinkIsBlockedBy = "is_blocked_by"
+)
+
+// IssueLink is a link from an issue to another issue
+type IssueLink struct {
+	Iid      int    `json:"iid"`
+	LinkType string `json:"link_type"`
+	// the path of the project of the linked issue (empty for the same project)
+	Project string `json:"project,omitempty"`
+}
+
+// AwardEmoji is an emoji reaction of a gitlab user to an issue or a note
+type AwardEmoji struct {
+	// the name of the emoji (e.g. thumbsup)
+	Name   string `json:"name"`
+	UserId int    `json:"user_id"`
+}
+
+// SummarizeAwards returns the emoji and their counts (in descending order)
+// as markdown (e.g. ":thumbsup: 3, :tada: 1")
+func SummarizeAwards(awards []*AwardEmoji) string {
+	counts := map[string]int{}
+	names := []string{}
+	for _, award := range awards {
+		if counts[award.Name] == 0 {
+			names = append(names, award.Name)
+		}
+		counts[award.Name] += 1
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	summary := []string{}
+	for _, name := range names {
+		summary = append(summary, fmt.Sprintf(":%s: %d", name, counts[name]))
+	}
+	return strings.Join(summary, ", ")
+}
+
+// awardsLine returns the line of the rendered body that summarizes the awards (if any)
+func awardsLine(awards []*AwardEmoji) string {
+	if len(awards) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("reactions: %s\n", SummarizeAwards(awards))
+}
+
+func (issue Issue) Convert(opts Options) (string, error) {
 	author := fmt.Sprintf("%d", issue.AuthorId)
-	if ghname, ok := mappings[issue.AuthorId]; ok {
-		author = "@" + ghname
+	if ghname, ok := opts.Mappings[issue.AuthorId]; ok {
+		author = opts.Attribution.Format(ghname)
 	}
 
-	description, err := ReplaceAll(issue.Description, replPatterns)
+	description, err := opts.render(issue.Description)
 	if err != nil {
 		return "", err
 	}
@@ -40,13 +109,20 @@ func (issue Issue) Convert(mappings map[int]string, replPatterns map[string]stri
 		closedAt = issue.ClosedAt.Format(time.RFC3339)
 	}
 
-	return fmt.Sprintf("\nISSUE IMPORTED FROM GITLAB [id=%d] [state=%s]\ncreated: `%s`\nclosed: `%s`\noriginal author: %s\ncomments: %d\n\n---\n\n%s",
+	original := ""
+	if url, ok := opts.IssueURL(issue.Id); ok {
+		original = fmt.Sprintf("original issue: %s\n", url)
+	}
+
+	return fmt.Sprintf("\nISSUE IMPORTED FROM GITLAB [id=%d] [state=%s]\ncreated: `%s`\nclosed: `%s`\noriginal author: %s\ncomments: %d\n%s%s\n---\n\n%s",
 		issue.Id,
 		issue.State,
 		issue.CreatedAt.Format(time.RFC3339),
 		closedAt,
 		author,
 		len(issue.Comments),
+		awardsLine(issue.AwardEmoji),
+		original,
 		description), nil
 }
 
@@ -54,11 +130,17 @@ func (issue Issue) Summarize() string {
 	return fmt.Sprintf("[%d] [uid=%d] [comments=%d] %s\n", issue.Id, issue.AuthorId, len(issue.Comments), issue.Title)
 }
 
+// IsTask returns true if the issue is a child task of another issue
+func (issue Issue) IsTask() bool {
+	return issue.Type == TypeTask && issue.ParentId > 0
+}
+
 func (issue Issue) IsClosed() bool {
 	return !issue.ClosedAt.IsZero()
 }
 
 type Comment struct {
+	Id           int       `json:"id"`
 	Note         string    `json:"note"`
 	AuthorId     int       `json:"author_id"`
 	DiscussionId string    `json:"discussion_id"`
@@ -66,16 +148,25 @@ type Comment struct {
 	Author       struct {
 		Name string `json:"name"`
 	} `json:"author"`
+	AwardEmoji []*AwardEmoji `json:"award_emoji"`
+	// the ID of the issue that the note belongs to (set when the issues are parsed)
+	IssueId int `json:"-"`
 }
 
-func (c Comment) Convert(replPatterns map[string]string) (string, error) {
-	description, err := ReplaceAll(c.Note, replPatterns)
+func (c Comment) Convert(opts Options) (string, error) {
+	description, err := opts.render(c.Note)
 	if err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("\nCOMMENT IMPORTED FROM GITLAB\ncreated: `%s`\noriginal author: %s\n\n---\n\n%s",
+	original := ""
+	if url, ok := opts.IssueURL(c.IssueId); ok && c.IssueId > 0 && c.Id > 0 {
+		original = fmt.Sprintf("original note: %s#note_%d\n", url, c.Id)
+	}
+	return fmt.Sprintf("\nCOMMENT IMPORTED FROM GITLAB\ncreated: `%s`\noriginal author: %s\n%s%s\n---\n\n%s",
 		c.CreatedAt.Format(time.RFC3339),
 		c.Author.Name,
+		awardsLine(c.AwardEmoji),
+		original,
 		description), nil
 }
 
@@ -113,6 +204,9 @@ func curateIssues(issues []*Issue, commentExclusionFilter []string) []*Issue {
 	// filter and sort comments
 	for _, issue := range issues {
 		issue.Comments = filterComments(issue.Comments, commentExclusionFilter)
+		for _, comment := range issue.Comments {
+			comment.IssueId = issue.Id
+		}
 		sort.Slice(issue.Comments, func(i, j int) bool {
 			return issue.Comments[i].CreatedAt.Before(issue.Comments[j].CreatedAt)
 		})
@@ -25,3 +25,21 @@ func Test_Replace(t *testing.T) {
 		assert.Equal(t, kase.expected, out)
 	}
 }
+
+func Test_Convert_Backlinks(t *testing.T) {
+	comment := &Comment{Id: 101, Note: "A comment", IssueId: 1}
+	issue := &Issue{Id: 1, Title: "An issue", Description: "A description", Comments: []*Comment{comment}}
+
+	// no links without the URL of gitlab
+	body, err := issue.Convert(Options{Project: "group/project"})
+	assert.Nil(t, err)
+	assert.NotContains(t, body, "original issue:")
+
+	opts := Options{Project: "group/project", GitlabURL: "https://gitlab.example.com/"}
+	body, err = issue.Convert(opts)
+	assert.Nil(t, err)
+	assert.Contains(t, body, "\noriginal issue: https://gitlab.example.com/group/project/-/issues/1\n")
+	body, err = comment.Convert(opts)
+	assert.Nil(t, err)
+	assert.Contains(t, body, "\noriginal note: https://gitlab.example.com/group/project/-/issues/1#note_101\n")
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/kkentzo/gl-to-gh/cmd"
@@ -15,6 +16,7 @@ var mappings = map[int]string{
 func main() {
 	root := cmd.New()
 	if err := root.Execute(); err != nil {
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
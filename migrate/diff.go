@@ -0,0 +1,79 @@
+package migrate
+
+import "strings"
+
+// the maximum size of the LCS table of Diff (about 8MB)
+// beyond it the differing lines are listed as removed and then added
+const maxDiffCells = 1 << 20
+
+// Diff returns a line-based diff between the texts a and b
+// Removed lines are prefixed with "-", added lines with "+" and common lines with " "
+func Diff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// the common prefix and suffix are kept out of the LCS table
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var sb strings.Builder
+	for _, line := range x[:prefix] {
+		sb.WriteString(" " + line + "\n")
+	}
+	diffLines(&sb, x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])
+	for _, line := range x[len(x)-suffix:] {
+		sb.WriteString(" " + line + "\n")
+	}
+	return sb.String()
+}
+
+// diffLines writes the diff between the lines x and y to sb
+func diffLines(sb *strings.Builder, x, y []string) {
+	if (len(x)+1)*(len(y)+1) > maxDiffCells {
+		for _, line := range x {
+			sb.WriteString("-" + line + "\n")
+		}
+		for _, line := range y {
+			sb.WriteString("+" + line + "\n")
+		}
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			sb.WriteString(" " + x[i] + "\n")
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] > lcs[i+1][j]):
+			sb.WriteString("+" + y[j] + "\n")
+			j++
+		default:
+			sb.WriteString("-" + x[i] + "\n")
+			i++
+		}
+	}
+}
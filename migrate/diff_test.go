@@ -0,0 +1,35 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Diff(t *testing.T) {
+	kases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a\nb\nc", "a\nb\nc", " a\n b\n c\n"},
+		{"a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c\n"},
+		{"a\nc", "a\nb\nc", " a\n+b\n c\n"},
+		{"a\nb", "b", "-a\n b\n"},
+	}
+
+	for _, kase := range kases {
+		assert.Equal(t, kase.expected, Diff(kase.a, kase.b))
+	}
+}
+
+func Test_Diff_Large(t *testing.T) {
+	// the differing lines exceed the LCS table, the common ones are still kept
+	a := "header\n" + strings.Repeat("a\n", 2000) + "footer"
+	b := "header\n" + strings.Repeat("b\n", 2000) + "footer"
+	diff := Diff(a, b)
+	assert.True(t, strings.HasPrefix(diff, " header\n-a\n"))
+	assert.True(t, strings.HasSuffix(diff, "+b\n footer\n"))
+	assert.Equal(t, 2000, strings.Count(diff, "-a\n"))
+	assert.Equal(t, 2000, strings.Count(diff, "+b\n"))
+}
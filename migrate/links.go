@@ -0,0 +1,234 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// where the related issues are listed
+const (
+	LinksNone    = "none"
+	LinksComment = "comment"
+	LinksBody    = "body"
+)
+
+// how the child tasks of the issues are migrated
+const (
+	TasksSubIssues = "sub-issues"
+	TasksList      = "list"
+)
+
+// link lists the related issues of every issue in the range (in its body or a comment,
+// depending on Options.Links) using the github numbers of the mapping and, if the target
+// supports dependencies, marks the blocked issues as blocked by their blockers.
+// The child tasks of the issues are added as sub-issues (or listed as a task list in their body,
+// see Options.Tasks). It runs after all the issues have been created, so that the numbers are known.
+func (m *Migrator) link(ctx context.Context) error {
+	dependencies, _ := m.target.(github.Dependencies)
+	subIssues, _ := m.target.(github.SubIssues)
+	if m.opts.Tasks == TasksList {
+		subIssues = nil
+	}
+	children := m.children()
+	migrated := m.migrated()
+	for _, iid := range m.iids() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		number, mapped := m.opts.Mapping.Number(iid)
+		if !mapped {
+			continue
+		}
+
+		tasks := children[iid]
+		if subIssues != nil && len(tasks) > 0 {
+			err := m.addSubIssues(ctx, subIssues, number, tasks)
+			if errors.Is(err, github.ErrSubIssuesUnsupported) {
+				// fall back to task lists for the rest of the run
+				subIssues = nil
+			} else if err != nil {
+				err = fmt.Errorf("[#%d] %w", iid, err)
+				m.fail(iid, err)
+				return err
+			} else {
+				tasks = nil
+			}
+		}
+
+		links := m.links(iid)
+		var body, comment string
+		switch {
+		case m.opts.Links == LinksBody && len(links)+len(tasks) > 0:
+			body = github.LinksSection(links, tasks)
+		case len(tasks) > 0:
+			body = github.LinksSection(nil, tasks)
+		}
+		if m.opts.Links == LinksComment && len(links) > 0 {
+			comment = github.LinksSection(links, nil)
+		}
+		if body != "" {
+			if err := m.editBody(ctx, iid, number, body); err != nil {
+				m.fail(iid, err)
+				return err
+			}
+		}
+		if comment != "" {
+			if err := m.postComment(ctx, iid, number, comment); err != nil {
+				m.fail(iid, err)
+				return err
+			}
+		}
+
+		if dependencies == nil {
+			continue
+		}
+		err := m.addDependencies(ctx, dependencies, migrated, iid, number, links)
+		if errors.Is(err, github.ErrDependenciesUnsupported) {
+			// the links are still listed in the issues
+			dependencies = nil
+		} else if err != nil {
+			err = fmt.Errorf("[#%d] %w", iid, err)
+			m.fail(iid, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// addDependencies marks the issue number (and the issues that it blocks) as blocked by the linked issues
+// (migrated maps the github numbers to the gitlab issues they were migrated from)
+func (m *Migrator) addDependencies(ctx context.Context, target github.Dependencies, migrated map[int]int, iid, number int, links []github.Link) error {
+	for _, link := range links {
+		if link.Number == 0 {
+			continue
+		}
+		// each dependency is added from the side of the blocked issue only
+		// (gitlab lists the link in both issues)
+		if link.Type == gitlab.LinkIsBlockedBy {
+			if err := target.AddBlockedBy(detach(ctx), m.opts.Repo, number, link.Number); err != nil {
+				return err
+			}
+		} else if link.Type == gitlab.LinkBlocks && !m.hasLink(migrated[link.Number], iid, gitlab.LinkIsBlockedBy) {
+			if err := target.AddBlockedBy(detach(ctx), m.opts.Repo, link.Number, number); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// links returns the links of the gitlab issue iid
+// (they are needed for the dependencies even if they are not listed)
+func (m *Migrator) links(iid int) []github.Link {
+	source, ok := m.sources[iid]
+	if !ok {
+		return nil
+	}
+	links := []github.Link{}
+	for _, l := range source.Links {
+		link := github.Link{Type: l.LinkType, Reference: fmt.Sprintf("#%d", l.Iid)}
+		if l.Project != "" && l.Project != m.opts.Render.Project {
+			link.Reference = fmt.Sprintf("%s#%d", l.Project, l.Iid)
+		} else if linked, ok := m.opts.Mapping.Number(l.Iid); ok {
+			link.Number = linked
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// children returns the migrated child tasks of the gitlab issues indexed by the ID of their parent
+func (m *Migrator) children() map[int][]github.Task {
+	iids := []int{}
+	for iid, source := range m.sources {
+		if source.IsTask() {
+			iids = append(iids, iid)
+		}
+	}
+	sort.Ints(iids)
+	children := map[int][]github.Task{}
+	for _, iid := range iids {
+		source := m.sources[iid]
+		if number, ok := m.opts.Mapping.Number(iid); ok {
+			children[source.ParentId] = append(children[source.ParentId], github.Task{Number: number, Closed: source.IsClosed()})
+		}
+	}
+	return children
+}
+
+func (m *Migrator) addSubIssues(ctx context.Context, target github.SubIssues, number int, tasks []github.Task) error {
+	for _, task := range tasks {
+		if err := target.AddSubIssue(detach(ctx), m.opts.Repo, number, task.Number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrated returns the gitlab issues indexed by the github number they were migrated as
+func (m *Migrator) migrated() map[int]int {
+	migrated := map[int]int{}
+	for iid := range m.sources {
+		if number, ok := m.opts.Mapping.Number(iid); ok {
+			migrated[number] = iid
+		}
+	}
+	return migrated
+}
+
+// hasLink returns true if the gitlab issue source links to iid with linkType
+func (m *Migrator) hasLink(source, iid int, linkType string) bool {
+	issue, ok := m.sources[source]
+	if !ok {
+		return false
+	}
+	for _, l := range issue.Links {
+		if l.Iid == iid && l.LinkType == linkType && (l.Project == "" || l.Project == m.opts.Render.Project) {
+			return true
+		}
+	}
+	return false
+}
+
+// editBody adds (or replaces) the section of related issues and tasks in the body of the issue
+func (m *Migrator) editBody(ctx context.Context, iid, number int, section string) error {
+	issue, err := m.target.GetIssue(detach(ctx), m.opts.Repo, number)
+	if err != nil {
+		return fmt.Errorf("[#%d] %w", iid, err)
+	}
+	if body := github.WithLinks(issue.Body, section); body != issue.Body {
+		if err := m.target.UpdateIssue(detach(ctx), m.opts.Repo, number, github.IssueUpdate{Body: body}); err != nil {
+			return fmt.Errorf("[#%d] failed to add the related issues to #%d: %w", iid, number, err)
+		}
+	}
+	return nil
+}
+
+// postComment adds (or replaces) the comment that lists the related issues
+func (m *Migrator) postComment(ctx context.Context, iid, number int, section string) error {
+	comments, err := m.target.ListComments(detach(ctx), m.opts.Repo, number)
+	if err != nil {
+		return fmt.Errorf("[#%d] failed to list the comments of issue #%d: %w", iid, number, err)
+	}
+	for _, comment := range comments {
+		if github.IsLinksSection(comment.Body) {
+			if comment.Body == section {
+				return nil
+			}
+			if err := comment.Edit(detach(ctx), m.target, m.opts.Repo, section); err != nil {
+				return fmt.Errorf("[#%d] failed to update the related issues of #%d: %w", iid, number, err)
+			}
+			return nil
+		}
+	}
+	comment := &github.Comment{Body: section}
+	if err := comment.Post(detach(ctx), m.target, m.opts.Repo, number); err != nil {
+		return fmt.Errorf("[#%d] failed to add the related issues to #%d: %w", iid, number, err)
+	}
+	return nil
+}
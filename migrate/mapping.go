@@ -0,0 +1,137 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Mapping records the github counterparts of migrated gitlab items
+// It is safe for concurrent use through its methods
+type Mapping struct {
+	mu sync.RWMutex
+
+	// github issue numbers indexed by gitlab issue ID
+	Issues map[int]int `json:"issues"`
+	// github comment IDs indexed by gitlab note ID
+	Notes map[int]int64 `json:"notes"`
+
+	path string
+}
+
+func NewMapping() *Mapping {
+	return &Mapping{Issues: map[int]int{}, Notes: map[int]int64{}}
+}
+
+// LoadMapping reads the mapping from the file at path
+// An empty mapping is returned if the file does not exist yet
+// and the mapping will be saved to path in any case
+func LoadMapping(path string) (*Mapping, error) {
+	m := NewMapping()
+	m.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %v", path, err)
+	}
+	if m.Issues == nil {
+		m.Issues = map[int]int{}
+	}
+	if m.Notes == nil {
+		m.Notes = map[int]int64{}
+	}
+	return m, nil
+}
+
+// Number returns the github issue number of the gitlab issue iid
+func (m *Mapping) Number(iid int) (int, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	number, ok := m.Issues[iid]
+	return number, ok
+}
+
+// SetNumber records the github issue number of the gitlab issue iid
+func (m *Mapping) SetNumber(iid, number int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Issues[iid] = number
+}
+
+// Iids returns the IDs of the mapped gitlab issues in ascending order
+func (m *Mapping) Iids() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	iids := make([]int, 0, len(m.Issues))
+	for iid := range m.Issues {
+		iids = append(iids, iid)
+	}
+	sort.Ints(iids)
+	return iids
+}
+
+// Comment returns the github comment ID of the gitlab note
+func (m *Mapping) Comment(note int) (int64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.Notes[note]
+	return id, ok
+}
+
+// SetComment records the github comment ID of the gitlab note
+func (m *Mapping) SetComment(note int, id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Notes[note] = id
+}
+
+// Discard detaches the mapping from its file so that the changes are never saved (e.g. for dry runs)
+func (m *Mapping) Discard() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.path = ""
+}
+
+// Save writes the mapping to the file it was loaded from (if any)
+func (m *Mapping) Save() error {
+	if m.path == "" {
+		return nil
+	}
+	// hold the write lock so that concurrent saves do not race on the file
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := writeJSON(m.path, m); err != nil {
+		return fmt.Errorf("failed to save mapping: %v", err)
+	}
+	return nil
+}
+
+// writeJSON writes v to path atomically so that an interrupted write does not corrupt the file
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
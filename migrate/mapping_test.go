@@ -0,0 +1,27 @@
+package migrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Mapping_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mapping.json")
+
+	m, err := LoadMapping(path)
+	assert.Nil(t, err)
+	assert.Empty(t, m.Issues)
+
+	m.Issues[3] = 5
+	m.Notes[42] = 1001
+	assert.Nil(t, m.Save())
+
+	loaded, err := LoadMapping(path)
+	assert.Nil(t, err)
+	number, ok := loaded.Number(3)
+	assert.True(t, ok)
+	assert.Equal(t, 5, number)
+	assert.Equal(t, int64(1001), loaded.Notes[42])
+}
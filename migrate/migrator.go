@@ -0,0 +1,401 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// Events are invoked by the Migrator as the migration progresses
+// Any of the callbacks can be nil; with multiple workers they are invoked concurrently
+type Events struct {
+	IssueCreated       func(iid int, issue *github.Issue)
+	PlaceholderCreated func(iid int, issue *github.Issue)
+	CommentPosted      func(iid int, issue *github.Issue, comment *github.Comment)
+	// the issue had already been imported (and its body was updated if updated=true)
+	IssueExisting func(iid int, existing *github.Issue, updated bool)
+	// the re-rendered body differs from the body of the github issue or comment
+	IssueChanged   func(iid int, number int, diff string)
+	CommentChanged func(iid int, id int64, diff string)
+	// the changes of an already migrated issue were synced (e.g. "title", "closed", "2 comments")
+	IssueSynced func(iid int, number int, changes []string)
+	Error       func(iid int, err error)
+}
+
+// policies for issues that already exist in the github repo
+const (
+	ExistingSkip   = "skip"
+	ExistingUpdate = "update"
+)
+
+type Options struct {
+	// the target github repo in the form 'user_or_org/repo_name'
+	Repo string
+	// labels to be attached to every created issue
+	Labels []string
+	// the range of gitlab issue IDs to be migrated (End=0 means the last issue)
+	Start int
+	End   int
+	// post the comments of already imported issues (instead of creating the issues)
+	// the target issues are resolved through Mapping
+	CommentsOnly bool
+	// iterate issue IDs in reverse order (only with CommentsOnly)
+	Reverse bool
+	// number of issues whose comments are posted concurrently (only with CommentsOnly)
+	Workers int
+	// what to do with issues that have already been imported (ExistingSkip or ExistingUpdate)
+	Existing string
+	// options for converting gitlab issues and comments
+	Render gitlab.Options
+	// the github counterparts of migrated items (updated and saved during the run)
+	Mapping *Mapping
+	// where the related (linked) issues are listed after the issues are created:
+	// LinksBody (the default), LinksComment or LinksNone
+	Links string
+	// how the child tasks are attached to their parent issues: TasksSubIssues (the default;
+	// falls back to TasksList if the target does not support sub-issues) or TasksList
+	Tasks  string
+	Events Events
+}
+
+type Migrator struct {
+	target   github.Target
+	opts     Options
+	issueMap map[int]*github.Issue
+	// the gitlab issues indexed by ID
+	sources map[int]*gitlab.Issue
+	last    int
+	report  *Report
+	// already imported github issues indexed by gitlab issue ID
+	existing map[int]*github.Issue
+}
+
+// New converts the gitlab issues to their github counterparts
+// and returns a Migrator that is ready to post them to github
+func New(target github.Target, issues []*gitlab.Issue, opts Options) (*Migrator, error) {
+	if len(issues) == 0 {
+		return nil, fmt.Errorf("no issues to migrate")
+	}
+	if opts.Reverse && !opts.CommentsOnly {
+		return nil, fmt.Errorf("reverse order can be specified only in conjuction with comments")
+	}
+	if opts.Mapping == nil {
+		if opts.CommentsOnly {
+			return nil, fmt.Errorf("importing comments requires the mapping of issue IDs to github issue numbers")
+		}
+		opts.Mapping = NewMapping()
+	}
+	switch opts.Links {
+	case "":
+		opts.Links = LinksBody
+	case LinksComment, LinksBody, LinksNone:
+	default:
+		return nil, fmt.Errorf("unknown placement of related issues: %s", opts.Links)
+	}
+	switch opts.Tasks {
+	case "":
+		opts.Tasks = TasksSubIssues
+	case TasksSubIssues, TasksList:
+	default:
+		return nil, fmt.Errorf("unknown migration of tasks: %s", opts.Tasks)
+	}
+	switch opts.Existing {
+	case "":
+		opts.Existing = ExistingSkip
+	case ExistingSkip, ExistingUpdate:
+	default:
+		return nil, fmt.Errorf("unknown policy for existing issues: %s", opts.Existing)
+	}
+
+	m := &Migrator{
+		target:   target,
+		opts:     opts,
+		issueMap: map[int]*github.Issue{},
+		sources:  map[int]*gitlab.Issue{},
+		last:     issues[len(issues)-1].Id,
+		report:   NewReport(),
+		existing: map[int]*github.Issue{},
+	}
+	for _, issue := range issues {
+		ghIssue, err := github.New(issue, opts.Labels, opts.Render)
+		if err != nil {
+			return nil, fmt.Errorf("[#%d] failed to convert issue: %v", issue.Id, err)
+		}
+		m.issueMap[issue.Id] = ghIssue
+		m.sources[issue.Id] = issue
+	}
+	return m, nil
+}
+
+// Range returns the first and last issue IDs of the migration in iteration order
+func (m *Migrator) Range() (start, end int) {
+	start, end = m.opts.Start, m.last
+	if m.opts.End > 0 {
+		end = m.opts.End
+	}
+	if m.opts.Reverse {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// Report returns the report of the migration (complete only after Run returns)
+func (m *Migrator) Report() *Report {
+	return m.report
+}
+
+// Run performs the migration until all the issue IDs in the range have been processed,
+// an error occurs or ctx is cancelled. Cancelling ctx does not interrupt the API call
+// that is in flight; the run stops cleanly right after it completes.
+func (m *Migrator) Run(ctx context.Context) (err error) {
+	m.report = NewReport()
+	defer func() { m.report.Finish(m.target.RequestCount(), err) }()
+
+	if err = m.scan(ctx); err != nil {
+		return err
+	}
+
+	iids := m.iids()
+	if m.opts.CommentsOnly {
+		return m.runComments(ctx, iids)
+	}
+
+	// issues are created strictly sequentially so that github numbers follow the gitlab IDs
+	for _, iid := range iids {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = m.postIssue(ctx, iid)
+		if err == nil {
+			err = m.opts.Mapping.Save()
+		}
+		if err != nil {
+			m.fail(iid, err)
+			return err
+		}
+	}
+	return m.link(ctx)
+}
+
+// iids returns the issue IDs of the range in iteration order
+func (m *Migrator) iids() []int {
+	start, end := m.Range()
+	step := 1
+	if m.opts.Reverse {
+		step = -1
+	}
+	iids := []int{}
+	for iid := start; (step > 0 && iid <= end) || (step < 0 && iid >= end); iid += step {
+		iids = append(iids, iid)
+	}
+	return iids
+}
+
+// runComments posts the comments of the issues using a pool of workers
+// Each issue is handled by a single worker so that its comments are posted in order
+func (m *Migrator) runComments(ctx context.Context, iids []int) error {
+	workers := m.opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// cancelled on the first failure so that the other workers stop after their current comment
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		jobs     = make(chan int)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for iid := range jobs {
+				err := m.postComments(runCtx, iid)
+				if err == nil {
+					err = m.opts.Mapping.Save()
+				}
+				if err != nil {
+					m.fail(iid, err)
+					once.Do(func() {
+						firstErr = err
+						stop()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, iid := range iids {
+		select {
+		case jobs <- iid:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// scan the github repo for issues that have already been imported from the gitlab project
+// and add them to the mapping
+func (m *Migrator) scan(ctx context.Context) error {
+	issues, err := m.target.ListIssues(ctx, m.opts.Repo)
+	if err != nil {
+		return fmt.Errorf("failed to scan existing issues: %w", err)
+	}
+	for _, issue := range issues {
+		if p, ok := issue.Provenance(); ok && p.Note == 0 && p.Matches(m.opts.Render.Project) {
+			if _, dup := m.existing[p.Iid]; !dup {
+				m.existing[p.Iid] = issue
+				m.opts.Mapping.SetNumber(p.Iid, issue.Number)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) postIssue(ctx context.Context, iid int) error {
+	if existing, ok := m.existing[iid]; ok {
+		return m.handleExisting(ctx, iid, existing)
+	}
+	if issue, ok := m.issueMap[iid]; ok {
+		if err := issue.Post(detach(ctx), m.target, m.opts.Repo); err != nil {
+			return fmt.Errorf("[#%d] failed to POST issue: %w", iid, err)
+		}
+		m.opts.Mapping.SetNumber(iid, issue.Number)
+		m.report.record(&Outcome{Iid: iid, Number: issue.Number, Outcome: OutcomeCreated})
+		if m.opts.Events.IssueCreated != nil {
+			m.opts.Events.IssueCreated(iid, issue)
+		}
+	} else {
+		// create placeholder issue
+		issue := github.NewPlaceholder(m.opts.Labels, github.Provenance{Project: m.opts.Render.Project, Iid: iid})
+		if err := issue.Post(detach(ctx), m.target, m.opts.Repo); err != nil {
+			return fmt.Errorf("[#%d] failed to POST placeholder issue: %w", iid, err)
+		}
+		m.opts.Mapping.SetNumber(iid, issue.Number)
+		m.report.record(&Outcome{Iid: iid, Number: issue.Number, Outcome: OutcomePlaceholder})
+		if m.opts.Events.PlaceholderCreated != nil {
+			m.opts.Events.PlaceholderCreated(iid, issue)
+		}
+	}
+	return nil
+}
+
+// skip or update an issue that has already been imported
+func (m *Migrator) handleExisting(ctx context.Context, iid int, existing *github.Issue) error {
+	issue, ok := m.issueMap[iid]
+	var body string
+	if ok {
+		body = github.KeepLinks(issue.FinalBody(), existing.Body)
+	}
+	if m.opts.Existing == ExistingSkip || !ok || body == existing.Body {
+		m.report.record(&Outcome{Iid: iid, Number: existing.Number, Outcome: OutcomeExisting})
+		if m.opts.Events.IssueExisting != nil {
+			m.opts.Events.IssueExisting(iid, existing, false)
+		}
+		return nil
+	}
+	issue.Number = existing.Number
+	if err := issue.Edit(detach(ctx), m.target, m.opts.Repo, body); err != nil {
+		return fmt.Errorf("[#%d] failed to update issue #%d: %w", iid, existing.Number, err)
+	}
+	m.report.record(&Outcome{Iid: iid, Number: existing.Number, Outcome: OutcomeUpdated})
+	if m.opts.Events.IssueExisting != nil {
+		m.opts.Events.IssueExisting(iid, existing, true)
+	}
+	return nil
+}
+
+// post the comments of the issue iid to its github counterpart
+// comments that already exist in the github issue are skipped
+func (m *Migrator) postComments(ctx context.Context, iid int) error {
+	issue, ok := m.issueMap[iid]
+	number, found := m.opts.Mapping.Number(iid)
+	if !ok || !found {
+		m.report.record(&Outcome{Iid: iid, Outcome: OutcomeSkipped})
+		return nil
+	}
+	outcome := &Outcome{Iid: iid, Number: number, Outcome: OutcomeComments}
+	m.report.record(outcome)
+
+	existing, err := m.target.ListComments(detach(ctx), m.opts.Repo, number)
+	if err != nil {
+		return fmt.Errorf("[#%d] failed to list the comments of issue #%d: %w", iid, number, err)
+	}
+
+	for _, comment := range issue.Comments() {
+		if ctx.Err() != nil {
+			// stop right after the last posted comment
+			return nil
+		}
+		if id, ok := findComment(existing, comment); ok {
+			m.recordComment(comment, id)
+			outcome.SkippedComments += 1
+			continue
+		}
+		if err := comment.Post(detach(ctx), m.target, m.opts.Repo, number); err != nil {
+			return fmt.Errorf("[#%d] failed to post comment: %w", iid, err)
+		}
+		m.recordComment(comment, comment.Id)
+		outcome.Comments += 1
+		if m.opts.Events.CommentPosted != nil {
+			m.opts.Events.CommentPosted(iid, issue, comment)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) recordComment(comment *github.Comment, id int64) {
+	if p, ok := comment.Provenance(); ok && p.Note > 0 {
+		m.opts.Mapping.SetComment(p.Note, id)
+	}
+}
+
+// findComment returns the ID of the github comment that corresponds to comment
+// Comments are matched by their provenance marker or (lacking one) by their body
+func findComment(existing []*github.Comment, comment *github.Comment) (int64, bool) {
+	p, hasMarker := comment.Provenance()
+	for _, c := range existing {
+		if hasMarker {
+			if q, ok := c.Provenance(); ok && q == p {
+				return c.Id, true
+			}
+		} else if c.Body == comment.Body {
+			return c.Id, true
+		}
+	}
+	return 0, false
+}
+
+func (m *Migrator) fail(iid int, err error) {
+	m.report.fail(iid, err)
+	if m.opts.Events.Error != nil {
+		m.opts.Events.Error(iid, err)
+	}
+}
+
+// Issue returns the converted github issue for the gitlab issue ID iid
+func (m *Migrator) Issue(iid int) (*github.Issue, bool) {
+	issue, ok := m.issueMap[iid]
+	return issue, ok
+}
+
+// detach returns a context that lets an API call in flight complete
+// (waiting for the rate limits is still interrupted by ctx)
+func detach(ctx context.Context) context.Context {
+	return github.Detach(ctx)
+}
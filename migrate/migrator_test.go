@@ -0,0 +1,129 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRepo = "o/r"
+
+// commentTarget records the comments in the order they are posted
+// and lets the tests interfere with posting them
+type commentTarget struct {
+	*github.Simulator
+
+	mu     sync.Mutex
+	posted map[int][]string
+	count  int
+	// called before each comment is posted (with the number of comments posted so far)
+	before func(count, number int) error
+}
+
+func (t *commentTarget) CreateComment(ctx context.Context, repo string, number int, comment *github.Comment) error {
+	t.mu.Lock()
+	count := t.count
+	t.mu.Unlock()
+	if t.before != nil {
+		if err := t.before(count, number); err != nil {
+			return err
+		}
+	}
+	// give the other workers the chance to interleave
+	time.Sleep(time.Millisecond)
+	if err := t.Simulator.CreateComment(ctx, repo, number, comment); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count += 1
+	t.posted[number] = append(t.posted[number], comment.Body)
+	return nil
+}
+
+// newCommentsMigrator returns a migrator that posts the comments of n issues (with n comments each)
+// to issues that already exist in the target
+func newCommentsMigrator(t *testing.T, n, workers int) (*Migrator, *commentTarget) {
+	target := &commentTarget{Simulator: github.NewSimulator(nil), posted: map[int][]string{}}
+	mapping := NewMapping()
+	issues := []*gitlab.Issue{}
+	for iid := 1; iid <= n; iid++ {
+		issue := &gitlab.Issue{Id: iid, Title: fmt.Sprintf("issue %d", iid), CreatedAt: time.Now()}
+		for c := 0; c < n; c++ {
+			issue.Comments = append(issue.Comments, &gitlab.Comment{Id: iid*100 + c, Note: fmt.Sprintf("note %d", c), IssueId: iid})
+		}
+		issues = append(issues, issue)
+		created := &github.Issue{Title: issue.Title}
+		assert.Nil(t, target.Simulator.CreateIssue(context.Background(), testRepo, created))
+		mapping.SetNumber(iid, created.Number)
+	}
+	m, err := New(target, issues, Options{Repo: testRepo, CommentsOnly: true, Workers: workers, Mapping: mapping})
+	assert.Nil(t, err)
+	return m, target
+}
+
+// assertInOrder asserts that the comments of each issue were posted in the order of their notes
+func assertInOrder(t *testing.T, target *commentTarget) {
+	for number, bodies := range target.posted {
+		for idx, body := range bodies {
+			assert.True(t, strings.Contains(body, fmt.Sprintf("note %d", idx)), "comment %d of issue #%d is out of order", idx, number)
+		}
+	}
+}
+
+func Test_RunComments_KeepsTheOrderOfEachIssue(t *testing.T) {
+	m, target := newCommentsMigrator(t, 6, 3)
+	assert.Nil(t, m.Run(context.Background()))
+	assert.Len(t, target.posted, 6)
+	for _, bodies := range target.posted {
+		assert.Len(t, bodies, 6)
+	}
+	assertInOrder(t, target)
+}
+
+func Test_RunComments_StopsOnCancellation(t *testing.T) {
+	m, target := newCommentsMigrator(t, 6, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	target.before = func(count, number int) error {
+		if count == 4 {
+			cancel()
+		}
+		return nil
+	}
+
+	assert.ErrorIs(t, m.Run(ctx), context.Canceled)
+	// the comments in flight are completed, but no new ones are started
+	assert.LessOrEqual(t, target.count, 4+3)
+	assert.Less(t, target.count, 36)
+	assertInOrder(t, target)
+}
+
+func Test_RunComments_StopsOnFailure(t *testing.T) {
+	m, target := newCommentsMigrator(t, 6, 3)
+	failure := errors.New("failure")
+	target.before = func(count, number int) error {
+		if number == 2 {
+			return failure
+		}
+		return nil
+	}
+
+	assert.ErrorIs(t, m.Run(context.Background()), failure)
+	assert.Empty(t, target.posted[2])
+	assert.Less(t, target.count, 36)
+	assertInOrder(t, target)
+	for _, outcome := range m.Report().Issues {
+		if outcome.Iid == 2 {
+			assert.Equal(t, OutcomeFailed, outcome.Outcome)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// marks the notes that redirect gitlab issues to github (hidden when rendered)
+const redirectMarker = "<!-- gl2gh:moved -->"
+
+// Source is the part of the gitlab API that is used to redirect the migrated issues
+type Source interface {
+	Issue(ctx context.Context, project string, iid int) (*gitlab.Issue, error)
+	Notes(ctx context.Context, project string, iid int) ([]*gitlab.Comment, error)
+	CreateNote(ctx context.Context, project string, iid int, body string) (*gitlab.Comment, error)
+	EditIssue(ctx context.Context, project string, iid int, edit gitlab.IssueEdit) error
+	RequestCount() int
+}
+
+type RedirectOptions struct {
+	// the gitlab project of the migrated issues
+	Project string
+	// the github repo in the form 'user_or_org/repo_name' and the web URL of github
+	Repo      string
+	GithubURL string
+	// the range of gitlab issue IDs to be redirected (End=0 means the last issue)
+	Start int
+	End   int
+	// close the gitlab issues and lock their discussions
+	Close bool
+	Lock  bool
+	// report the changes without making them
+	DryRun bool
+	// the github issue numbers of the migrated gitlab issues
+	Mapping *Mapping
+	// invoked after every redirected issue with the actions taken (e.g. "noted", "closed")
+	Redirected func(iid, number int, actions []string)
+}
+
+// RedirectNotice returns the note that points the readers of a gitlab issue to its github counterpart
+func RedirectNotice(url string) string {
+	return fmt.Sprintf("This issue has been moved to GitHub: %s\n\nPlease continue the discussion there.\n\n%s", url, redirectMarker)
+}
+
+// RedirectSource adds a note to each migrated gitlab issue that links to its github counterpart
+// and optionally closes the issue and locks its discussion.
+// Issues that have already been redirected do not get a second note, and issues that are
+// already closed (or locked) are not edited again.
+func RedirectSource(ctx context.Context, source Source, opts RedirectOptions) (report *Report, err error) {
+	report = NewReport()
+	defer func() { report.Finish(source.RequestCount(), err) }()
+	if opts.Mapping == nil {
+		return report, fmt.Errorf("a mapping is required")
+	}
+
+	for _, iid := range opts.Mapping.Iids() {
+		if iid < opts.Start || (opts.End > 0 && iid > opts.End) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+		if err = redirectIssue(ctx, source, opts, iid, report); err != nil {
+			report.fail(iid, err)
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func redirectIssue(ctx context.Context, source Source, opts RedirectOptions, iid int, report *Report) error {
+	number, _ := opts.Mapping.Number(iid)
+	outcome := &Outcome{Iid: iid, Number: number, Outcome: OutcomeUnchanged}
+
+	issue, err := source.Issue(ctx, opts.Project, iid)
+	var serr *gitlab.StatusError
+	if errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound {
+		// the gitlab issue has been deleted (its github counterpart is a placeholder)
+		outcome.Outcome = OutcomeSkipped
+		report.record(outcome)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("[#%d] %w", iid, err)
+	}
+	notes, err := source.Notes(ctx, opts.Project, iid)
+	if err != nil {
+		return fmt.Errorf("[#%d] %w", iid, err)
+	}
+
+	actions := []string{}
+	if !hasRedirect(notes) {
+		if !opts.DryRun {
+			if _, err := source.CreateNote(ctx, opts.Project, iid, RedirectNotice(github.IssueURL(opts.GithubURL, opts.Repo, number))); err != nil {
+				return fmt.Errorf("[#%d] %w", iid, err)
+			}
+		}
+		actions = append(actions, "noted")
+	}
+	edit := gitlab.IssueEdit{}
+	if opts.Close && !issue.IsClosed() {
+		edit.StateEvent = "close"
+		actions = append(actions, "closed")
+	}
+	if opts.Lock && !issue.DiscussionLocked {
+		locked := true
+		edit.DiscussionLocked = &locked
+		actions = append(actions, "locked")
+	}
+	if edit != (gitlab.IssueEdit{}) && !opts.DryRun {
+		if err := source.EditIssue(ctx, opts.Project, iid, edit); err != nil {
+			return fmt.Errorf("[#%d] %w", iid, err)
+		}
+	}
+
+	if len(actions) > 0 && opts.DryRun {
+		outcome.Outcome = OutcomeChanged
+	} else if len(actions) > 0 {
+		outcome.Outcome = OutcomeUpdated
+	}
+	report.record(outcome)
+	if len(actions) > 0 && opts.Redirected != nil {
+		opts.Redirected(iid, number, actions)
+	}
+	return nil
+}
+
+func hasRedirect(notes []*gitlab.Comment) bool {
+	for _, note := range notes {
+		if strings.Contains(note.Note, redirectMarker) {
+			return true
+		}
+	}
+	return false
+}
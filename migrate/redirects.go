@@ -0,0 +1,213 @@
+package migrate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// the formats of the redirect configuration
+const (
+	RedirectsNginx  = "nginx"
+	RedirectsApache = "apache"
+	RedirectsCaddy  = "caddy"
+	RedirectsHTML   = "html"
+	RedirectsCSV    = "csv"
+)
+
+var RedirectFormats = []string{RedirectsNginx, RedirectsApache, RedirectsCaddy, RedirectsHTML, RedirectsCSV}
+
+// Redirect maps a gitlab issue to its github counterpart
+type Redirect struct {
+	Iid int
+	// the web URL of the github issue
+	URL string
+	// github comment IDs indexed by gitlab note ID (as strings, for the html pages)
+	Notes map[string]int64
+}
+
+// Redirects maps the URLs of the migrated gitlab issues to the URLs of the github issues
+type Redirects struct {
+	// the path of the gitlab project (e.g. group/project)
+	Project   string
+	Redirects []*Redirect
+}
+
+// NewRedirects returns the redirects of the issues in the mapping
+// The redirects of notes (#note_N anchors) are known only for the notes of
+// the specified gitlab issues (if any), since the mapping does not record their issue.
+func NewRedirects(mapping *Mapping, issues []*gitlab.Issue, project, githubURL, repo string) *Redirects {
+	notes := map[int][]int{}
+	for _, issue := range issues {
+		for _, comment := range issue.Comments {
+			notes[issue.Id] = append(notes[issue.Id], comment.Id)
+		}
+	}
+	r := &Redirects{Project: strings.Trim(project, "/")}
+	for _, iid := range mapping.Iids() {
+		number, _ := mapping.Number(iid)
+		redirect := &Redirect{Iid: iid, URL: github.IssueURL(githubURL, repo, number), Notes: map[string]int64{}}
+		for _, note := range notes[iid] {
+			if id, ok := mapping.Comment(note); ok {
+				redirect.Notes[strconv.Itoa(note)] = id
+			}
+		}
+		r.Redirects = append(r.Redirects, redirect)
+	}
+	return r
+}
+
+// Paths returns the paths of the gitlab issue in both of gitlab's URL forms
+func (r *Redirects) Paths(iid int) []string {
+	return []string{
+		fmt.Sprintf("/%s/issues/%d", r.Project, iid),
+		fmt.Sprintf("/%s/-/issues/%d", r.Project, iid),
+	}
+}
+
+// Write writes the redirects in the format (one of RedirectFormats) to w
+// The html format is written to a directory instead (see WriteHTML)
+func (r *Redirects) Write(w io.Writer, format string) error {
+	switch format {
+	case RedirectsNginx:
+		return r.WriteNginx(w)
+	case RedirectsApache:
+		return r.WriteApache(w)
+	case RedirectsCaddy:
+		return r.WriteCaddy(w)
+	case RedirectsCSV:
+		return r.WriteCSV(w)
+	default:
+		return fmt.Errorf("unknown redirect format %q (one of: %v)", format, RedirectFormats)
+	}
+}
+
+// WriteNginx writes an nginx map from the gitlab paths to the github URLs
+// Anchors are not sent to the server, so the redirects of notes end up at the top of the github issue.
+func (r *Redirects) WriteNginx(w io.Writer) error {
+	fmt.Fprintf(w, "# include in the http block and redirect with:\n")
+	fmt.Fprintf(w, "#   if ($gitlab_issue_redirect) { return 301 $gitlab_issue_redirect; }\n")
+	fmt.Fprintf(w, "map $uri $gitlab_issue_redirect {\n")
+	fmt.Fprintf(w, "    default \"\";\n")
+	for _, redirect := range r.Redirects {
+		for _, path := range r.Paths(redirect.Iid) {
+			fmt.Fprintf(w, "    %s %s;\n", path, redirect.URL)
+		}
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// WriteApache writes a text RewriteMap from the gitlab paths to the github URLs
+func (r *Redirects) WriteApache(w io.Writer) error {
+	fmt.Fprintf(w, "# use as a text RewriteMap:\n")
+	fmt.Fprintf(w, "#   RewriteMap gitlab-issues \"txt:/path/to/this/file\"\n")
+	fmt.Fprintf(w, "#   RewriteCond ${gitlab-issues:%%{REQUEST_URI}} !=\"\"\n")
+	fmt.Fprintf(w, "#   RewriteRule ^ ${gitlab-issues:%%{REQUEST_URI}} [R=301,L,NE]\n")
+	for _, redirect := range r.Redirects {
+		for _, path := range r.Paths(redirect.Iid) {
+			if _, err := fmt.Fprintf(w, "%s %s\n", path, redirect.URL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteCaddy writes a Caddyfile snippet with the redirects
+func (r *Redirects) WriteCaddy(w io.Writer) error {
+	fmt.Fprintf(w, "# import the snippet in the site block with: import gitlab_issues\n")
+	fmt.Fprintf(w, "(gitlab_issues) {\n")
+	for _, redirect := range r.Redirects {
+		for _, path := range r.Paths(redirect.Iid) {
+			fmt.Fprintf(w, "    redir %s %s permanent\n", path, redirect.URL)
+		}
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// WriteCSV writes the redirects as (gitlab path, github URL) rows,
+// including a row for every note whose comment is known
+func (r *Redirects) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"gitlab", "github"})
+	for _, redirect := range r.Redirects {
+		notes := make([]string, 0, len(redirect.Notes))
+		for note := range redirect.Notes {
+			notes = append(notes, note)
+		}
+		sort.Slice(notes, func(i, j int) bool {
+			a, _ := strconv.Atoi(notes[i])
+			b, _ := strconv.Atoi(notes[j])
+			return a < b
+		})
+		for _, path := range r.Paths(redirect.Iid) {
+			out.Write([]string{path, redirect.URL})
+			for _, note := range notes {
+				out.Write([]string{
+					fmt.Sprintf("%s#note_%s", path, note),
+					fmt.Sprintf("%s#issuecomment-%d", redirect.URL, redirect.Notes[note])})
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+var redirectPage = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Moved to GitHub</title>
+<link rel="canonical" href="{{.URL}}">
+<script>
+var notes = {{.Notes}};
+var url = {{.URL}};
+var match = window.location.hash.match(/^#note_(\d+)$/);
+if (match && notes[match[1]]) {
+  url += "#issuecomment-" + notes[match[1]];
+}
+window.location.replace(url);
+</script>
+<meta http-equiv="refresh" content="0; url={{.URL}}">
+</head>
+<body>
+<p>This issue has moved to <a href="{{.URL}}">{{.URL}}</a>.</p>
+</body>
+</html>
+`))
+
+// WriteHTML writes a static page for each gitlab path (as <path>/index.html under dir)
+// that redirects to the github issue; links to notes are redirected to their comments
+func (r *Redirects) WriteHTML(dir string) error {
+	for _, redirect := range r.Redirects {
+		for _, path := range r.Paths(redirect.Iid) {
+			pageDir := filepath.Join(dir, filepath.FromSlash(path))
+			if err := os.MkdirAll(pageDir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %v", pageDir, err)
+			}
+			f, err := os.Create(filepath.Join(pageDir, "index.html"))
+			if err != nil {
+				return fmt.Errorf("failed to create page: %v", err)
+			}
+			err = redirectPage.Execute(f, redirect)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write page for %s: %v", path, err)
+			}
+		}
+	}
+	return nil
+}
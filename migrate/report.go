@@ -0,0 +1,152 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	OutcomeCreated     = "created"
+	OutcomePlaceholder = "placeholder"
+	OutcomeComments    = "comments"
+	OutcomeSkipped     = "skipped"
+	// the issue had already been imported
+	OutcomeExisting = "existing"
+	// the issue had already been imported and its body was updated
+	OutcomeUpdated = "updated"
+	// the issue and its comments did not need to be updated
+	OutcomeUnchanged = "unchanged"
+	// the issue or its comments need to be updated, but the run only previewed the changes
+	OutcomeChanged = "changed"
+	OutcomeFailed    = "failed"
+)
+
+// Outcome records what happened to a single gitlab issue during a run
+type Outcome struct {
+	Iid      int    `json:"iid"`
+	Number   int    `json:"number,omitempty"`
+	Outcome  string `json:"outcome"`
+	Comments int    `json:"comments"`
+	// comments that already existed in the github issue
+	SkippedComments int `json:"skipped_comments,omitempty"`
+	// existing comments that were edited to match their gitlab notes
+	UpdatedComments int `json:"updated_comments,omitempty"`
+	// existing comments that differ from their gitlab notes (when the changes are only previewed)
+	ChangedComments int    `json:"changed_comments,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Report is a machine-readable account of a migration run
+type Report struct {
+	mu sync.Mutex
+
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Duration   string     `json:"duration"`
+	Requests   int        `json:"requests"`
+	Issues     []*Outcome `json:"issues"`
+	Created    []int      `json:"created"`
+	Skipped    []int      `json:"skipped"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func NewReport() *Report {
+	return &Report{
+		StartedAt: time.Now(),
+		Issues:    []*Outcome{},
+		Created:   []int{},
+		Skipped:   []int{},
+	}
+}
+
+func (r *Report) record(outcome *Outcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Issues = append(r.Issues, outcome)
+	switch outcome.Outcome {
+	case OutcomeCreated, OutcomePlaceholder:
+		r.Created = append(r.Created, outcome.Number)
+	case OutcomeSkipped, OutcomeExisting:
+		r.Skipped = append(r.Skipped, outcome.Iid)
+	}
+}
+
+// outcome returns the latest outcome recorded for the issue iid
+func (r *Report) outcome(iid int) *Outcome {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.Issues) - 1; i >= 0; i-- {
+		if r.Issues[i].Iid == iid {
+			return r.Issues[i]
+		}
+	}
+	outcome := &Outcome{Iid: iid}
+	r.Issues = append(r.Issues, outcome)
+	return outcome
+}
+
+func (r *Report) fail(iid int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the issue may have been already recorded (e.g. while posting its comments)
+	for i := len(r.Issues) - 1; i >= 0; i-- {
+		if r.Issues[i].Iid == iid {
+			r.Issues[i].Outcome = OutcomeFailed
+			r.Issues[i].Error = err.Error()
+			return
+		}
+	}
+	r.Issues = append(r.Issues, &Outcome{Iid: iid, Outcome: OutcomeFailed, Error: err.Error()})
+}
+
+// Finish marks the end of the run with its final error (if any)
+func (r *Report) Finish(requests int, err error) {
+	r.FinishedAt = time.Now()
+	r.Duration = r.FinishedAt.Sub(r.StartedAt).String()
+	r.Requests = requests
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Summarize returns a human-readable account of what the run has completed
+func (r *Report) Summarize() string {
+	counts := map[string]int{}
+	comments, updated, existing, changed := 0, 0, 0, 0
+	for _, outcome := range r.Issues {
+		counts[outcome.Outcome] += 1
+		comments += outcome.Comments
+		updated += outcome.UpdatedComments
+		existing += outcome.SkippedComments
+		changed += outcome.ChangedComments
+	}
+	s := fmt.Sprintf("Issues: %d created, %d placeholders, %d already existed, %d updated, %d unchanged, %d with comments, %d skipped, %d failed\nComments: %d posted, %d updated, %d already existed\n",
+		counts[OutcomeCreated], counts[OutcomePlaceholder], counts[OutcomeExisting], counts[OutcomeUpdated], counts[OutcomeUnchanged],
+		counts[OutcomeComments], counts[OutcomeSkipped], counts[OutcomeFailed], comments, updated, existing)
+	if counts[OutcomeChanged] > 0 {
+		s += fmt.Sprintf("Previewed updates: %d issues, %d comments\n", counts[OutcomeChanged], changed)
+	}
+	if n := len(r.Issues); n > 0 {
+		s += fmt.Sprintf("Last processed issue: #%d (%s)\n", r.Issues[n-1].Iid, r.Issues[n-1].Outcome)
+	}
+	s += fmt.Sprintf("Duration: %s\nRequest Count: %d", r.Duration, r.Requests)
+	if r.Error != "" {
+		s += fmt.Sprintf("\nError: %s", r.Error)
+	}
+	return s
+}
+
+// Write serializes the report as JSON to the file at path
+func (r *Report) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize report: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report to %s: %v", path, err)
+	}
+	return nil
+}
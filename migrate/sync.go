@@ -0,0 +1,166 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kkentzo/gl-to-gh/github"
+)
+
+// Sync brings the github repo up to date with the gitlab issues in the range:
+// new issues are created (with their comments), new notes are appended as comments
+// and the titles and states (closed/reopened) of the already migrated issues are updated.
+// Issues that have not been updated in gitlab since their last sync point are skipped.
+// Finally, the related issues are listed (see Options.Links).
+func (m *Migrator) Sync(ctx context.Context, state *SyncState) (err error) {
+	m.report = NewReport()
+	defer func() { m.report.Finish(m.target.RequestCount(), err) }()
+
+	if err = m.scan(ctx); err != nil {
+		return err
+	}
+
+	for _, iid := range m.iids() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = m.syncIssue(ctx, iid, state)
+		if err == nil {
+			err = m.opts.Mapping.Save()
+		}
+		if err == nil {
+			err = state.Save()
+		}
+		if err != nil {
+			m.fail(iid, err)
+			return err
+		}
+	}
+	return m.link(ctx)
+}
+
+func (m *Migrator) syncIssue(ctx context.Context, iid int, state *SyncState) error {
+	source, ok := m.sources[iid]
+	number, mapped := m.opts.Mapping.Number(iid)
+	if !mapped {
+		// a new issue (or a placeholder for a deleted one)
+		if err := m.postIssue(ctx, iid); err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		number, _ = m.opts.Mapping.Number(iid)
+		posted, err := m.syncComments(ctx, iid, number, nil, false, m.report.outcome(iid))
+		if err != nil {
+			return err
+		}
+		state.SetPoint(iid, &SyncPoint{UpdatedAt: source.UpdatedAt, Notes: posted})
+		return nil
+	}
+	if !ok {
+		m.report.record(&Outcome{Iid: iid, Number: number, Outcome: OutcomeSkipped})
+		return nil
+	}
+
+	point, synced := state.Point(iid)
+	outcome := &Outcome{Iid: iid, Number: number, Outcome: OutcomeUnchanged}
+	m.report.record(outcome)
+	if synced && !source.UpdatedAt.After(point.UpdatedAt) {
+		return nil
+	}
+
+	live, ok := m.existing[iid]
+	if !ok {
+		var err error
+		if live, err = m.target.GetIssue(detach(ctx), m.opts.Repo, number); err != nil {
+			return fmt.Errorf("[#%d] %w", iid, err)
+		}
+	}
+
+	changes := []string{}
+	update := github.IssueUpdate{}
+	if source.Title != live.Title {
+		update.Title = source.Title
+		changes = append(changes, "title")
+	}
+	if update.SetClosed(live, source.IsClosed()) {
+		if source.IsClosed() {
+			changes = append(changes, "closed")
+		} else {
+			changes = append(changes, "reopened")
+		}
+	}
+	if update != (github.IssueUpdate{}) {
+		if err := m.target.UpdateIssue(detach(ctx), m.opts.Repo, number, update); err != nil {
+			return fmt.Errorf("[#%d] failed to update issue #%d: %w", iid, number, err)
+		}
+		outcome.Outcome = OutcomeUpdated
+	}
+
+	// without a sync point the comments of the issue are matched against the existing ones
+	var notes []int
+	if synced {
+		notes = point.Notes
+	}
+	posted, err := m.syncComments(ctx, iid, number, notes, !synced, outcome)
+	if err != nil {
+		return err
+	}
+	if outcome.Comments > 0 {
+		outcome.Outcome = OutcomeUpdated
+		changes = append(changes, fmt.Sprintf("%d comments", outcome.Comments))
+	}
+	state.SetPoint(iid, &SyncPoint{UpdatedAt: source.UpdatedAt, Notes: posted})
+
+	if len(changes) > 0 && m.opts.Events.IssueSynced != nil {
+		m.opts.Events.IssueSynced(iid, number, changes)
+	}
+	return nil
+}
+
+// syncComments posts the comments of issue iid whose notes are not in synced
+// If match is true, comments that already exist in the github issue are skipped.
+// It returns the IDs of all the synced notes of the issue.
+func (m *Migrator) syncComments(ctx context.Context, iid, number int, synced []int, match bool, outcome *Outcome) ([]int, error) {
+	issue := m.issueMap[iid]
+	point := &SyncPoint{Notes: synced}
+	for _, comment := range issue.Comments() {
+		// comments without a marker can only be matched by their body
+		if _, ok := comment.Provenance(); !ok {
+			match = true
+		}
+	}
+	var existing []*github.Comment
+	if match {
+		var err error
+		if existing, err = m.target.ListComments(detach(ctx), m.opts.Repo, number); err != nil {
+			return nil, fmt.Errorf("[#%d] failed to list the comments of issue #%d: %w", iid, number, err)
+		}
+	}
+
+	notes := append([]int{}, synced...)
+	for _, comment := range issue.Comments() {
+		p, hasMarker := comment.Provenance()
+		if hasMarker && point.HasNote(p.Note) {
+			continue
+		}
+		if id, ok := findComment(existing, comment); ok {
+			m.recordComment(comment, id)
+			outcome.SkippedComments += 1
+		} else {
+			if err := comment.Post(detach(ctx), m.target, m.opts.Repo, number); err != nil {
+				return nil, fmt.Errorf("[#%d] failed to post comment: %w", iid, err)
+			}
+			m.recordComment(comment, comment.Id)
+			outcome.Comments += 1
+			if m.opts.Events.CommentPosted != nil {
+				m.opts.Events.CommentPosted(iid, issue, comment)
+			}
+		}
+		if hasMarker {
+			notes = append(notes, p.Note)
+		}
+	}
+	return notes, nil
+}
@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SyncPoint is the state of a gitlab issue as of the last sync
+type SyncPoint struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	// the IDs of the gitlab notes that have been posted as comments
+	Notes []int `json:"notes"`
+}
+
+// SyncState records the last sync point of every synced gitlab issue
+// It is safe for concurrent use through its methods
+type SyncState struct {
+	mu sync.RWMutex
+
+	// sync points indexed by gitlab issue ID
+	Issues map[int]*SyncPoint `json:"issues"`
+
+	path string
+}
+
+func NewSyncState() *SyncState {
+	return &SyncState{Issues: map[int]*SyncPoint{}}
+}
+
+// LoadSyncState reads the sync state from the file at path
+// An empty state is returned if the file does not exist yet
+// and the state will be saved to path in any case
+func LoadSyncState(path string) (*SyncState, error) {
+	s := NewSyncState()
+	s.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state file %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse sync state file %s: %v", path, err)
+	}
+	if s.Issues == nil {
+		s.Issues = map[int]*SyncPoint{}
+	}
+	return s, nil
+}
+
+// Point returns the last sync point of the gitlab issue iid
+func (s *SyncState) Point(iid int) (*SyncPoint, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	point, ok := s.Issues[iid]
+	return point, ok
+}
+
+// SetPoint records the sync point of the gitlab issue iid
+func (s *SyncState) SetPoint(iid int, point *SyncPoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Issues[iid] = point
+}
+
+// Discard detaches the state from its file so that the changes are never saved (e.g. for dry runs)
+func (s *SyncState) Discard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = ""
+}
+
+// Save writes the state to the file it was loaded from (if any)
+func (s *SyncState) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := writeJSON(s.path, s); err != nil {
+		return fmt.Errorf("failed to save sync state: %v", err)
+	}
+	return nil
+}
+
+// HasNote returns true if the note with the specified ID has been synced
+func (p *SyncPoint) HasNote(id int) bool {
+	for _, note := range p.Notes {
+		if note == id {
+			return true
+		}
+	}
+	return false
+}
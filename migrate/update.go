@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kkentzo/gl-to-gh/github"
+)
+
+// Update re-renders the issues in the range with the current options and updates
+// the bodies of the already imported github issues and comments that have changed.
+// If preview is true, the differences are reported but nothing is updated.
+func (m *Migrator) Update(ctx context.Context, preview bool) (err error) {
+	m.report = NewReport()
+	defer func() { m.report.Finish(m.target.RequestCount(), err) }()
+
+	if err = m.scan(ctx); err != nil {
+		return err
+	}
+
+	start, end := m.Range()
+	if start > end {
+		start, end = end, start
+	}
+	for iid := start; iid <= end; iid++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = m.updateIssue(ctx, iid, preview); err != nil {
+			m.fail(iid, err)
+			return err
+		}
+	}
+	return m.opts.Mapping.Save()
+}
+
+func (m *Migrator) updateIssue(ctx context.Context, iid int, preview bool) error {
+	issue, ok := m.issueMap[iid]
+	number, found := m.opts.Mapping.Number(iid)
+	if !ok || !found {
+		m.report.record(&Outcome{Iid: iid, Outcome: OutcomeSkipped})
+		return nil
+	}
+	outcome := &Outcome{Iid: iid, Number: number, Outcome: OutcomeUnchanged}
+	m.report.record(outcome)
+
+	live, ok := m.existing[iid]
+	if !ok {
+		var err error
+		if live, err = m.target.GetIssue(detach(ctx), m.opts.Repo, number); err != nil {
+			return fmt.Errorf("[#%d] %w", iid, err)
+		}
+	}
+
+	if body := github.KeepLinks(issue.FinalBody(), live.Body); body != live.Body {
+		if m.opts.Events.IssueChanged != nil {
+			m.opts.Events.IssueChanged(iid, number, Diff(live.Body, body))
+		}
+		if preview {
+			outcome.Outcome = OutcomeChanged
+		} else {
+			issue.Number = number
+			if err := issue.Edit(detach(ctx), m.target, m.opts.Repo, body); err != nil {
+				return fmt.Errorf("[#%d] failed to update issue #%d: %w", iid, number, err)
+			}
+			outcome.Outcome = OutcomeUpdated
+		}
+	}
+
+	if len(issue.Comments()) == 0 {
+		return nil
+	}
+	existing, err := m.target.ListComments(detach(ctx), m.opts.Repo, number)
+	if err != nil {
+		return fmt.Errorf("[#%d] failed to list the comments of issue #%d: %w", iid, number, err)
+	}
+	for _, comment := range issue.Comments() {
+		if _, ok := comment.Provenance(); !ok {
+			// comments without a marker cannot be matched once their body has changed
+			continue
+		}
+		id, ok := findComment(existing, comment)
+		if !ok {
+			continue
+		}
+		m.recordComment(comment, id)
+		live := findCommentById(existing, id)
+		if live.Body == comment.Body {
+			continue
+		}
+		if m.opts.Events.CommentChanged != nil {
+			m.opts.Events.CommentChanged(iid, id, Diff(live.Body, comment.Body))
+		}
+		if preview {
+			outcome.ChangedComments += 1
+			outcome.Outcome = OutcomeChanged
+			continue
+		}
+		if err := live.Edit(detach(ctx), m.target, m.opts.Repo, comment.Body); err != nil {
+			return fmt.Errorf("[#%d] failed to update comment %d: %w", iid, id, err)
+		}
+		outcome.UpdatedComments += 1
+		outcome.Outcome = OutcomeUpdated
+	}
+	return nil
+}
+
+func findCommentById(comments []*github.Comment, id int64) *github.Comment {
+	for _, c := range comments {
+		if c.Id == id {
+			return c
+		}
+	}
+	return nil
+}
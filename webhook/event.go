@@ -0,0 +1,149 @@
+// Package webhook receives gitlab's issue and note webhooks and mirrors
+// the events to the migration target through a durable queue
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/gitlab"
+)
+
+// the values of the X-Gitlab-Event header that are mirrored
+const (
+	IssueHook = "Issue Hook"
+	NoteHook  = "Note Hook"
+)
+
+// Event is a webhook delivery as stored in the queue
+type Event struct {
+	Kind    string          `json:"kind"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// timestamp parses the time formats that gitlab uses in webhook payloads
+// (RFC 3339 or "2006-01-02 15:04:05 UTC")
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" {
+		// null
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown time format: %s", s)
+}
+
+type project struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type issueAttributes struct {
+	Iid         int       `json:"iid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	State       string    `json:"state"`
+	AuthorId    int       `json:"author_id"`
+	CreatedAt   timestamp `json:"created_at"`
+	UpdatedAt   timestamp `json:"updated_at"`
+	ClosedAt    timestamp `json:"closed_at"`
+}
+
+// IssuePayload is the payload of an Issue Hook
+type IssuePayload struct {
+	Project    project         `json:"project"`
+	Attributes issueAttributes `json:"object_attributes"`
+	Assignees  []struct {
+		Id int `json:"id"`
+	} `json:"assignees"`
+	// the attributes that were changed by an update (e.g. "title", "description")
+	Changes map[string]json.RawMessage `json:"changes"`
+}
+
+// issue returns the issue in the model of the export
+func (a *issueAttributes) issue() *gitlab.Issue {
+	issue := &gitlab.Issue{
+		Id:          a.Iid,
+		Title:       a.Title,
+		Description: a.Description,
+		AuthorId:    a.AuthorId,
+		State:       a.State,
+		CreatedAt:   a.CreatedAt.Time,
+		UpdatedAt:   a.UpdatedAt.Time,
+		ClosedAt:    a.ClosedAt.Time,
+		Comments:    []*gitlab.Comment{},
+	}
+	// closed_at is missing from older payloads
+	if issue.State == "closed" && issue.ClosedAt.IsZero() {
+		issue.ClosedAt = issue.UpdatedAt
+	}
+	return issue
+}
+
+// Issue returns the issue of the payload in the model of the export
+func (p *IssuePayload) Issue() *gitlab.Issue {
+	issue := p.Attributes.issue()
+	for _, assignee := range p.Assignees {
+		issue.Assignees = append(issue.Assignees, struct {
+			UserId int `json:"user_id"`
+		}{assignee.Id})
+	}
+	return issue
+}
+
+// Changed returns true if the update of the payload changed the attribute
+func (p *IssuePayload) Changed(attribute string) bool {
+	_, ok := p.Changes[attribute]
+	return ok
+}
+
+// NotePayload is the payload of a Note Hook
+type NotePayload struct {
+	Project project `json:"project"`
+	User    struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+	Attributes struct {
+		Id           int       `json:"id"`
+		Note         string    `json:"note"`
+		NoteableType string    `json:"noteable_type"`
+		AuthorId     int       `json:"author_id"`
+		System       bool      `json:"system"`
+		CreatedAt    timestamp `json:"created_at"`
+	} `json:"object_attributes"`
+	Issue issueAttributes `json:"issue"`
+}
+
+// Comment returns the note of the payload in the model of the export
+func (p *NotePayload) Comment() *gitlab.Comment {
+	comment := &gitlab.Comment{
+		Id:        p.Attributes.Id,
+		Note:      p.Attributes.Note,
+		AuthorId:  p.Attributes.AuthorId,
+		CreatedAt: p.Attributes.CreatedAt.Time,
+		IssueId:   p.Issue.Iid,
+	}
+	comment.Author.Name = p.User.Name
+	return comment
+}
+
+// excluded returns true if the note starts with any of the prefixes of the filter
+func excluded(note string, filter []string) bool {
+	for _, prefix := range filter {
+		if strings.HasPrefix(note, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+// maximum size of a webhook payload
+const maxPayload = 10 << 20
+
+// Handler receives gitlab webhooks and queues the issue and note events
+// Deliveries are acknowledged only after they have been stored in the queue.
+type Handler struct {
+	queue *Queue
+	// the secret token that gitlab sends in the X-Gitlab-Token header
+	secret string
+}
+
+func NewHandler(queue *Queue, secret string) *Handler {
+	return &Handler{queue: queue, secret: secret}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(h.secret)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	kind := r.Header.Get("X-Gitlab-Event")
+	if kind != IssueHook && kind != NoteHook {
+		// acknowledge the events that are not mirrored so that gitlab does not retry them
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayload))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(payload) {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	data, err := json.Marshal(&Event{Kind: kind, Payload: payload})
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.queue.Push(data); err != nil {
+		log.Printf("error: %v", err)
+		http.Error(w, "failed to queue event", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
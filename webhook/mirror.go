@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/kkentzo/gl-to-gh/migrate"
+)
+
+type Options struct {
+	// the target github repo in the form 'user_or_org/repo_name'
+	Repo string
+	// labels to be attached to every created issue
+	Labels []string
+	// options for converting gitlab issues and comments
+	Render gitlab.Options
+	// notes that start with any of these prefixes are not mirrored
+	CommentExclusionFilter []string
+	// the github counterparts of mirrored items (updated and saved after every event)
+	Mapping *migrate.Mapping
+}
+
+// Mirror applies gitlab webhook events to the target repo
+type Mirror struct {
+	target github.Target
+	opts   Options
+}
+
+func NewMirror(target github.Target, opts Options) *Mirror {
+	if opts.Mapping == nil {
+		opts.Mapping = migrate.NewMapping()
+	}
+	return &Mirror{target: target, opts: opts}
+}
+
+// permanentError marks the events that will never be applied (e.g. malformed payloads)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// IsPermanent returns true if retrying the event that caused err is pointless
+func IsPermanent(err error) bool {
+	var perr *permanentError
+	return errors.As(err, &perr)
+}
+
+// Apply mirrors the event to the target repo and returns a description of the outcome
+// Events of other projects and notes on anything other than issues are ignored.
+// Cancelling ctx does not interrupt the API call that is in flight, since github
+// may have applied it already (and a retry would duplicate it).
+func (m *Mirror) Apply(ctx context.Context, event *Event) (string, error) {
+	var (
+		outcome string
+		err     error
+	)
+	switch event.Kind {
+	case IssueHook:
+		payload := &IssuePayload{}
+		if err := json.Unmarshal(event.Payload, payload); err != nil {
+			return "", &permanentError{fmt.Errorf("invalid issue payload: %v", err)}
+		}
+		if !m.matches(payload.Project) {
+			return "ignored (other project)", nil
+		}
+		outcome, err = m.applyIssue(ctx, payload)
+	case NoteHook:
+		payload := &NotePayload{}
+		if err := json.Unmarshal(event.Payload, payload); err != nil {
+			return "", &permanentError{fmt.Errorf("invalid note payload: %v", err)}
+		}
+		if !m.matches(payload.Project) {
+			return "ignored (other project)", nil
+		}
+		outcome, err = m.applyNote(ctx, payload)
+	default:
+		return "", &permanentError{fmt.Errorf("unsupported event: %s", event.Kind)}
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := m.opts.Mapping.Save(); err != nil {
+		return "", err
+	}
+	return outcome, nil
+}
+
+// matches returns true if the event belongs to the migrated project (if known)
+func (m *Mirror) matches(p project) bool {
+	return m.opts.Render.Project == "" || p.PathWithNamespace == "" || p.PathWithNamespace == m.opts.Render.Project
+}
+
+func (m *Mirror) applyIssue(ctx context.Context, payload *IssuePayload) (string, error) {
+	source := payload.Issue()
+	iid := source.Id
+	issue, err := github.New(source, append([]string{}, m.opts.Labels...), m.opts.Render)
+	if err != nil {
+		return "", &permanentError{fmt.Errorf("[#%d] failed to convert issue: %v", iid, err)}
+	}
+
+	number, ok := m.opts.Mapping.Number(iid)
+	if !ok {
+		if err := m.createIssue(ctx, iid, issue); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[#%d] created as #%d", iid, issue.Number), nil
+	}
+
+	update := github.IssueUpdate{}
+	if payload.Changed("title") {
+		update.Title = issue.Title
+	}
+	stateChanged := payload.Changed("state_id") || payload.Changed("closed_at")
+	if payload.Changed("description") || stateChanged {
+		live, err := m.target.GetIssue(github.Detach(ctx), m.opts.Repo, number)
+		if err != nil {
+			return "", fmt.Errorf("[#%d] failed to fetch issue #%d: %w", iid, number, err)
+		}
+		if payload.Changed("description") {
+			// keep the related issues and tasks that were added to the body after the migration
+			update.Body = github.KeepLinks(keepCounters(issue.FinalBody(), live.Body), live.Body)
+		}
+		if stateChanged {
+			update.SetClosed(live, source.IsClosed())
+		}
+	}
+	if update == (github.IssueUpdate{}) {
+		return fmt.Sprintf("[#%d] issue #%d unchanged", iid, number), nil
+	}
+	if err := m.target.UpdateIssue(github.Detach(ctx), m.opts.Repo, number, update); err != nil {
+		return "", fmt.Errorf("[#%d] failed to update issue #%d: %w", iid, number, err)
+	}
+	return fmt.Sprintf("[#%d] issue #%d updated", iid, number), nil
+}
+
+// keepCounters returns the body with the comment count and reactions lines of the header of live,
+// as the payloads of the issue events carry neither the notes nor the award emoji of the issue
+func keepCounters(body, live string) string {
+	end := strings.Index(body, headerEnd)
+	liveEnd := strings.Index(live, headerEnd)
+	if end < 0 || liveEnd < 0 {
+		return body
+	}
+	comments, reactions := "", ""
+	for _, line := range strings.Split(live[:liveEnd], "\n") {
+		if strings.HasPrefix(line, "comments: ") {
+			comments = line
+		} else if strings.HasPrefix(line, "reactions: ") {
+			reactions = line
+		}
+	}
+	var header []string
+	for _, line := range strings.Split(body[:end], "\n") {
+		if strings.HasPrefix(line, "reactions: ") {
+			continue
+		}
+		if strings.HasPrefix(line, "comments: ") && comments != "" {
+			header = append(header, comments)
+			if reactions != "" {
+				header = append(header, reactions)
+			}
+			continue
+		}
+		header = append(header, line)
+	}
+	return strings.Join(header, "\n") + body[end:]
+}
+
+// headerEnd separates the header of the migrated issues from their description
+const headerEnd = "\n---\n\n"
+
+func (m *Mirror) createIssue(ctx context.Context, iid int, issue *github.Issue) error {
+	if err := issue.Post(github.Detach(ctx), m.target, m.opts.Repo); err != nil {
+		return fmt.Errorf("[#%d] failed to POST issue: %w", iid, err)
+	}
+	m.opts.Mapping.SetNumber(iid, issue.Number)
+	return nil
+}
+
+func (m *Mirror) applyNote(ctx context.Context, payload *NotePayload) (string, error) {
+	iid := payload.Issue.Iid
+	if payload.Attributes.NoteableType != "Issue" {
+		return fmt.Sprintf("ignored (note on %s)", payload.Attributes.NoteableType), nil
+	}
+	if payload.Attributes.System || excluded(payload.Attributes.Note, m.opts.CommentExclusionFilter) {
+		return fmt.Sprintf("[#%d] note %d excluded", iid, payload.Attributes.Id), nil
+	}
+	number, ok := m.opts.Mapping.Number(iid)
+	if !ok && payload.Issue.Title == "" {
+		// the issue event may still be in flight; the note will be retried
+		return "", fmt.Errorf("[#%d] issue has not been mirrored yet", iid)
+	}
+	if !ok {
+		// the issue was created before the webhook was set up:
+		// mirror it from the attributes of the note's issue
+		issue, err := github.New(payload.Issue.issue(), append([]string{}, m.opts.Labels...), m.opts.Render)
+		if err != nil {
+			return "", &permanentError{fmt.Errorf("[#%d] failed to convert issue: %v", iid, err)}
+		}
+		if err := m.createIssue(ctx, iid, issue); err != nil {
+			return "", err
+		}
+		number = issue.Number
+	}
+
+	source := payload.Comment()
+	comment, err := github.NewComment(source, iid, m.opts.Render)
+	if err != nil {
+		return "", &permanentError{fmt.Errorf("[#%d] failed to convert note %d: %v", iid, source.Id, err)}
+	}
+
+	if id, ok := m.opts.Mapping.Comment(source.Id); ok {
+		comment.Id = id
+		if err := m.target.EditComment(github.Detach(ctx), m.opts.Repo, id, comment.Body); err != nil {
+			return "", fmt.Errorf("[#%d] failed to update comment %d: %w", iid, id, err)
+		}
+		return fmt.Sprintf("[#%d] comment %d of issue #%d updated", iid, id, number), nil
+	}
+	if err := comment.Post(github.Detach(ctx), m.target, m.opts.Repo, number); err != nil {
+		return "", fmt.Errorf("[#%d] failed to post comment: %w", iid, err)
+	}
+	m.opts.Mapping.SetComment(source.Id, comment.Id)
+	return fmt.Sprintf("[#%d] comment %d posted to issue #%d", iid, comment.Id, number), nil
+}
+
+// the number of times that an event is applied before it is rejected
+const maxAttempts = 10
+
+// Run applies the queued events in order until ctx is cancelled
+// An event that fails is retried (after retry) before any later event, so that
+// the order of the events is preserved; events that can never be applied
+// (or that fail maxAttempts times) are rejected.
+func Run(ctx context.Context, queue *Queue, mirror *Mirror, retry time.Duration) error {
+	var (
+		head     string
+		attempts int
+	)
+	for ctx.Err() == nil {
+		name, data, ok, err := queue.Peek()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-queue.Notify():
+				continue
+			}
+		}
+
+		if name != head {
+			head, attempts = name, 0
+		}
+		attempts += 1
+
+		event := &Event{}
+		var outcome string
+		if err = json.Unmarshal(data, event); err != nil {
+			err = &permanentError{fmt.Errorf("invalid queued event: %v", err)}
+		} else {
+			outcome, err = mirror.Apply(ctx, event)
+		}
+		switch {
+		case err == nil:
+			log.Printf("[%s] %s", name, outcome)
+			if err := queue.Remove(name); err != nil {
+				return err
+			}
+		case IsPermanent(err) || attempts >= maxAttempts:
+			log.Printf("[%s] error: %v (event rejected after %d attempts)", name, err, attempts)
+			if err := queue.Reject(name); err != nil {
+				return err
+			}
+		default:
+			log.Printf("[%s] error: %v (retrying in %s)", name, err, retry)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retry):
+			}
+		}
+	}
+	return nil
+}
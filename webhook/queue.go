@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	queueExt = ".json"
+	// directory (inside the queue) for events that can never be applied
+	failedDir = "failed"
+)
+
+// Queue is a durable FIFO queue of events backed by a directory
+// Every event is stored in its own file, named after its sequence number,
+// so that queued events survive restarts
+type Queue struct {
+	dir string
+
+	mu     sync.Mutex
+	seq    uint64
+	notify chan struct{}
+	// with preserve, removed and rejected events are left in place and only skipped
+	preserve bool
+	skipped  map[string]bool
+}
+
+// OpenQueue opens (or creates) the queue in dir
+func OpenQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Join(dir, failedDir), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create queue: %v", err)
+	}
+	q := &Queue{dir: dir, notify: make(chan struct{}, 1), skipped: map[string]bool{}}
+	// the rejected events are included so that new events never reuse their names
+	for _, d := range []string{dir, filepath.Join(dir, failedDir)} {
+		names, err := eventFiles(d)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			continue
+		}
+		last := strings.TrimSuffix(names[len(names)-1], queueExt)
+		seq, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file in queue: %s", names[len(names)-1])
+		}
+		if seq > q.seq {
+			q.seq = seq
+		}
+	}
+	return q, nil
+}
+
+// Preserve makes Remove and Reject leave the events in the queue directory (e.g. for dry runs)
+// The events are only skipped by the Queue, so that a later run will find them again.
+func (q *Queue) Preserve() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.preserve = true
+}
+
+// eventFiles returns the names of the event files in dir in order
+func eventFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue: %v", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), queueExt) && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	// sequence numbers are zero-padded so that their names sort in order
+	sort.Strings(names)
+	return names, nil
+}
+
+// names returns the names of the queued event files in order
+func (q *Queue) names() ([]string, error) {
+	all, err := eventFiles(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, name := range all {
+		if !q.skipped[name] {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Push appends the event to the queue
+// The event is written atomically and is durable when Push returns
+func (q *Queue) Push(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tmp, err := os.CreateTemp(q.dir, ".event-*")
+	if err != nil {
+		return fmt.Errorf("failed to queue event: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to queue event: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to queue event: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to queue event: %v", err)
+	}
+	name := fmt.Sprintf("%020d%s", q.seq+1, queueExt)
+	if err := os.Rename(tmp.Name(), filepath.Join(q.dir, name)); err != nil {
+		return fmt.Errorf("failed to queue event: %v", err)
+	}
+	q.seq += 1
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Peek returns the oldest event in the queue (ok is false if the queue is empty)
+func (q *Queue) Peek() (name string, data []byte, ok bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	names, err := q.names()
+	if err != nil || len(names) == 0 {
+		return "", nil, false, err
+	}
+	data, err = os.ReadFile(filepath.Join(q.dir, names[0]))
+	if err != nil {
+		return "", nil, false, fmt.Errorf("failed to read queued event: %v", err)
+	}
+	return names[0], data, true, nil
+}
+
+// Remove deletes the event from the queue (after it has been applied)
+func (q *Queue) Remove(name string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.preserve {
+		q.skipped[name] = true
+		return nil
+	}
+	if err := os.Remove(filepath.Join(q.dir, name)); err != nil {
+		return fmt.Errorf("failed to remove queued event: %v", err)
+	}
+	return nil
+}
+
+// Reject moves the event out of the queue into the directory of failed events
+func (q *Queue) Reject(name string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.preserve {
+		q.skipped[name] = true
+		return nil
+	}
+	if err := os.Rename(filepath.Join(q.dir, name), filepath.Join(q.dir, failedDir, name)); err != nil {
+		return fmt.Errorf("failed to reject queued event: %v", err)
+	}
+	return nil
+}
+
+// Len returns the number of queued events
+func (q *Queue) Len() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	names, err := q.names()
+	return len(names), err
+}
+
+// Notify returns a channel that receives a value whenever an event is pushed
+func (q *Queue) Notify() <-chan struct{} {
+	return q.notify
+}
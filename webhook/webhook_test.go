@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kkentzo/gl-to-gh/github"
+	"github.com/kkentzo/gl-to-gh/github/githubtest"
+	"github.com/kkentzo/gl-to-gh/gitlab"
+	"github.com/kkentzo/gl-to-gh/migrate"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	issueOpened = `{"object_kind":"issue","project":{"path_with_namespace":"group/project"},
+	"object_attributes":{"iid":3,"title":"An issue","description":"a description","state":"opened",
+	"author_id":5,"created_at":"2023-01-02 10:00:00 UTC","updated_at":"2023-01-02 10:00:00 UTC","closed_at":null}}`
+	issueClosed = `{"object_kind":"issue","project":{"path_with_namespace":"group/project"},
+	"object_attributes":{"iid":3,"title":"An issue (renamed)","description":"a description","state":"closed",
+	"author_id":5,"created_at":"2023-01-02T10:00:00Z","updated_at":"2023-01-03T10:00:00Z","closed_at":"2023-01-03T10:00:00Z"},
+	"changes":{"title":{"previous":"An issue","current":"An issue (renamed)"},"state_id":{"previous":1,"current":2}}}`
+	issueReopened = `{"object_kind":"issue","project":{"path_with_namespace":"group/project"},
+	"object_attributes":{"iid":3,"title":"An issue (renamed)","description":"a description","state":"opened",
+	"author_id":5,"created_at":"2023-01-02T10:00:00Z","updated_at":"2023-01-05T10:00:00Z","closed_at":null},
+	"changes":{"state_id":{"previous":2,"current":1},"closed_at":{"previous":"2023-01-03T10:00:00Z","current":null}}}`
+	issueEdited = `{"object_kind":"issue","project":{"path_with_namespace":"group/project"},
+	"object_attributes":{"iid":3,"title":"An issue (renamed)","description":"an edited description","state":"closed",
+	"author_id":5,"created_at":"2023-01-02T10:00:00Z","updated_at":"2023-01-04T10:00:00Z","closed_at":"2023-01-03T10:00:00Z"},
+	"changes":{"description":{"previous":"a description","current":"an edited description"}}}`
+	noteCreated = `{"object_kind":"note","project":{"path_with_namespace":"group/project"},"user":{"id":5,"name":"Jane"},
+	"object_attributes":{"id":41,"note":"a comment","noteable_type":"Issue","author_id":5,"created_at":"2023-01-02 11:00:00 UTC"},
+	"issue":{"iid":3}}`
+	noteUpdated = `{"object_kind":"note","project":{"path_with_namespace":"group/project"},"user":{"id":5,"name":"Jane"},
+	"object_attributes":{"id":41,"note":"an edited comment","noteable_type":"Issue","author_id":5,"created_at":"2023-01-02 11:00:00 UTC"},
+	"issue":{"iid":3}}`
+	noteOnOldIssue = `{"object_kind":"note","project":{"path_with_namespace":"group/project"},"user":{"id":5,"name":"Jane"},
+	"object_attributes":{"id":42,"note":"a late comment","noteable_type":"Issue","author_id":5,"created_at":"2023-01-02 11:00:00 UTC"},
+	"issue":{"iid":1,"title":"An old issue","description":"created before the webhook","state":"opened","author_id":5,
+	"created_at":"2022-01-02 10:00:00 UTC","updated_at":"2022-01-02 10:00:00 UTC"}}`
+	otherProject = `{"object_kind":"issue","project":{"path_with_namespace":"group/other"},"object_attributes":{"iid":9,"title":"x"}}`
+)
+
+func deliver(t *testing.T, h http.Handler, token, kind, payload string) int {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	req.Header.Set("X-Gitlab-Token", token)
+	req.Header.Set("X-Gitlab-Event", kind)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code
+}
+
+func Test_Handler(t *testing.T) {
+	queue, err := OpenQueue(t.TempDir())
+	assert.Nil(t, err)
+	h := NewHandler(queue, "secret")
+
+	assert.Equal(t, http.StatusUnauthorized, deliver(t, h, "wrong", IssueHook, issueOpened))
+	assert.Equal(t, http.StatusUnauthorized, deliver(t, h, "", IssueHook, issueOpened))
+	assert.Equal(t, http.StatusNoContent, deliver(t, h, "secret", "Push Hook", "{}"))
+	assert.Equal(t, http.StatusBadRequest, deliver(t, h, "secret", IssueHook, "not json"))
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", IssueHook, issueOpened))
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", NoteHook, noteCreated))
+
+	n, err := queue.Len()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func Test_Queue_SurvivesRestarts(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := OpenQueue(dir)
+	assert.Nil(t, err)
+	for _, data := range []string{"1", "2", "3"} {
+		assert.Nil(t, queue.Push([]byte(data)))
+	}
+	name, data, ok, err := queue.Peek()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(data))
+	assert.Nil(t, queue.Remove(name))
+
+	// reopened: pending events are kept in order and new ones go last
+	queue, err = OpenQueue(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Push([]byte("4")))
+	for _, expected := range []string{"2", "3", "4"} {
+		name, data, ok, err := queue.Peek()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, string(data))
+		assert.Nil(t, queue.Remove(name))
+	}
+	_, _, ok, err = queue.Peek()
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func Test_Queue_KeepsRejectedEvents(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := OpenQueue(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Push([]byte("1")))
+	name, _, _, err := queue.Peek()
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Reject(name))
+
+	// the drained queue is reopened: new events must not reuse the name of the rejected one
+	queue, err = OpenQueue(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Push([]byte("2")))
+	name, _, _, err = queue.Peek()
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Reject(name))
+	rejected, err := filepath.Glob(filepath.Join(dir, failedDir, "*.json"))
+	assert.Nil(t, err)
+	assert.Len(t, rejected, 2)
+}
+
+func Test_Queue_Preserve(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := OpenQueue(dir)
+	assert.Nil(t, err)
+	queue.Preserve()
+	assert.Nil(t, queue.Push([]byte("1")))
+	assert.Nil(t, queue.Push([]byte("2")))
+	name, _, _, err := queue.Peek()
+	assert.Nil(t, err)
+	assert.Nil(t, queue.Remove(name))
+	_, data, ok, err := queue.Peek()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "2", string(data))
+
+	// the events are still there for the next run
+	queue, err = OpenQueue(dir)
+	assert.Nil(t, err)
+	n, err := queue.Len()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func Test_Mirror(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	mapping, err := migrate.LoadMapping(filepath.Join(dir, "mapping.json"))
+	assert.Nil(t, err)
+	srv.AddIssue("o/r", "unrelated", "")
+
+	target := github.NewClient("token", false, false, github.WithEndpoint(srv.URL))
+	mirror := NewMirror(target, Options{
+		Repo:    "o/r",
+		Render:  gitlab.Options{Project: "group/project"},
+		Mapping: mapping,
+	})
+	ctx := context.Background()
+	apply := func(kind, payload string) error {
+		_, err := mirror.Apply(ctx, &Event{Kind: kind, Payload: []byte(payload)})
+		return err
+	}
+
+	// a note on an issue that has not been mirrored yet is retried later
+	err = apply(NoteHook, noteCreated)
+	assert.NotNil(t, err)
+	assert.False(t, IsPermanent(err))
+
+	assert.Nil(t, apply(IssueHook, issueOpened))
+	assert.Nil(t, apply(NoteHook, noteCreated))
+	assert.Nil(t, apply(NoteHook, noteUpdated))
+	assert.Nil(t, apply(IssueHook, issueClosed))
+	// the related issues that were added after the migration survive edits of the description
+	section := github.LinksSection([]github.Link{{Type: gitlab.LinkRelatesTo, Number: 1}}, nil)
+	srv.Repo("o/r").Issues[1].Body += "\n\n" + section
+	// as do the comment count and the reactions of the header, which the payloads do not carry
+	srv.Repo("o/r").Issues[1].Body = strings.Replace(srv.Repo("o/r").Issues[1].Body,
+		"comments: 0\n", "comments: 1\nreactions: :+1: 2\n", 1)
+	assert.Nil(t, apply(IssueHook, issueEdited))
+	assert.Nil(t, apply(IssueHook, otherProject))
+	assert.True(t, IsPermanent(apply("Push Hook", "{}")))
+	// the issue of a note is created from the note if it has not been mirrored
+	assert.Nil(t, apply(NoteHook, noteOnOldIssue))
+
+	repo := srv.Repo("o/r")
+	if assert.Len(t, repo.Issues, 3) {
+		issue := repo.Issues[1]
+		assert.Equal(t, "An issue (renamed)", issue.Title)
+		assert.Equal(t, "closed", issue.State)
+		assert.Contains(t, issue.Body, "an edited description")
+		assert.Contains(t, issue.Body, "comments: 1\nreactions: :+1: 2\n")
+		assert.True(t, strings.HasSuffix(issue.Body, section))
+		if assert.Len(t, issue.Comments, 1) {
+			assert.Contains(t, issue.Comments[0].Body, "an edited comment")
+		}
+		assert.Equal(t, "An old issue", repo.Issues[2].Title)
+		if assert.Len(t, repo.Issues[2].Comments, 1) {
+			assert.Contains(t, repo.Issues[2].Comments[0].Body, "a late comment")
+		}
+	}
+
+	// the mapping is persisted after every event
+	saved, err := migrate.LoadMapping(filepath.Join(dir, "mapping.json"))
+	assert.Nil(t, err)
+	number, ok := saved.Number(3)
+	assert.True(t, ok)
+	assert.Equal(t, 2, number)
+	_, ok = saved.Comment(41)
+	assert.True(t, ok)
+}
+
+func Test_Mirror_CloseAndReopen(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	target := github.NewClient("token", false, false, github.WithEndpoint(srv.URL))
+	mirror := NewMirror(target, Options{Repo: "o/r", Render: gitlab.Options{Project: "group/project"}})
+	apply := func(payload string) {
+		_, err := mirror.Apply(context.Background(), &Event{Kind: IssueHook, Payload: []byte(payload)})
+		assert.Nil(t, err)
+	}
+
+	apply(issueOpened)
+	apply(issueClosed)
+	issue := srv.Repo("o/r").Issues[0]
+	assert.Equal(t, "closed", issue.State)
+	// like imported issues, closed issues are labelled
+	assert.Equal(t, []string{github.ClosedLabel}, issue.Labels)
+
+	apply(issueReopened)
+	assert.Equal(t, "open", issue.State)
+	assert.Empty(t, issue.Labels)
+}
+
+func Test_Run_RetriesInOrder(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	queue, err := OpenQueue(t.TempDir())
+	assert.Nil(t, err)
+	h := NewHandler(queue, "secret")
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", IssueHook, issueOpened))
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", NoteHook, noteCreated))
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", "Issue Hook", "{\"object_attributes\":\"invalid\"}"))
+
+	// the first attempt to create the issue fails
+	srv.Fail(githubtest.Fault{Method: http.MethodPost, Path: "/repos/o/r/issues", Status: http.StatusBadGateway, Times: 1})
+	target := github.NewClient("token", false, false, github.WithEndpoint(srv.URL))
+	mirror := NewMirror(target, Options{Repo: "o/r", Render: gitlab.Options{Project: "group/project"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Run(ctx, queue, mirror, 10*time.Millisecond) }()
+	assert.Eventually(t, func() bool {
+		n, _ := queue.Len()
+		return n == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+
+	repo := srv.Repo("o/r")
+	if assert.Len(t, repo.Issues, 1) {
+		assert.Len(t, repo.Issues[0].Comments, 1)
+	}
+	rejected, err := filepath.Glob(filepath.Join(queue.dir, failedDir, "*.json"))
+	assert.Nil(t, err)
+	assert.Len(t, rejected, 1)
+}
+
+func Test_Run_RejectsAfterMaxAttempts(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	queue, err := OpenQueue(t.TempDir())
+	assert.Nil(t, err)
+	h := NewHandler(queue, "secret")
+	// the note's issue is never mirrored, so the note would block the queue forever
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", NoteHook, noteCreated))
+	assert.Equal(t, http.StatusAccepted, deliver(t, h, "secret", IssueHook, issueOpened))
+
+	target := github.NewClient("token", false, false, github.WithEndpoint(srv.URL))
+	mirror := NewMirror(target, Options{Repo: "o/r", Render: gitlab.Options{Project: "group/project"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Run(ctx, queue, mirror, time.Millisecond) }()
+	assert.Eventually(t, func() bool {
+		n, _ := queue.Len()
+		return n == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+
+	assert.Len(t, srv.Repo("o/r").Issues, 1)
+	rejected, err := filepath.Glob(filepath.Join(queue.dir, failedDir, "*.json"))
+	assert.Nil(t, err)
+	assert.Len(t, rejected, 1)
+}